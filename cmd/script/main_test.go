@@ -18,11 +18,19 @@ package main_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"strings"
 	"testing"
 
+	"knative.dev/hack"
 	main "knative.dev/hack/cmd/script"
 	"knative.dev/hack/pkg/inflator/cli"
+	"knative.dev/hack/pkg/inflator/extract"
 	"knative.dev/hack/pkg/utest/assert"
+	"knative.dev/hack/pkg/utest/require"
 )
 
 func TestMainFn(t *testing.T) {
@@ -45,6 +53,82 @@ func TestMainFn(t *testing.T) {
 	assert.ContainsSubstring(t, buf.String(), "Hacks as Go self-extracting binary")
 }
 
+func TestSubcommands(t *testing.T) {
+	tmpdir := t.TempDir()
+	t.Setenv(extract.HackScriptsDirEnvVar, tmpdir)
+	t.Setenv(cli.ManualVerboseEnvVar, "true")
+	library, err := hack.Scripts.ReadFile("library.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(library)
+
+	out, retcode := runMain(t, "list")
+	assert.Equal(t, 0, retcode)
+	assert.ContainsSubstring(t, out, "library.sh ")
+	assert.ContainsSubstring(t, out, hex.EncodeToString(sum[:])+"\n")
+
+	out, retcode = runMain(t, "cat", "library.sh")
+	assert.Equal(t, 0, retcode)
+	assert.Equal(t, string(library), out)
+	_, err = os.Stat(path.Join(tmpdir, "library.sh"))
+	assert.Equal(t, true, os.IsNotExist(err))
+
+	out, retcode = runMain(t, "path", "library.sh")
+	assert.Equal(t, 0, retcode)
+	assert.Equal(t, tmpdir+"/library.sh\n", out)
+
+	out, retcode = runMain(t, "-v", "library.sh")
+	assert.Equal(t, 0, retcode)
+	assert.ContainsSubstring(t, out, "library.sh                     up-to-date")
+	assert.ContainsSubstring(t, out, tmpdir+"/library.sh\n")
+
+	out, retcode = runMain(t, "verify", tmpdir)
+	assert.Equal(t, 0, retcode)
+	assert.Equal(t, "", out)
+
+	require.NoError(t, os.WriteFile(path.Join(tmpdir, "release.sh"), []byte("#!/bin/bash"), 0o644))
+	require.NoError(t, os.Remove(path.Join(tmpdir, "e2e-tests.sh")))
+	require.NoError(t, os.WriteFile(path.Join(tmpdir, "custom.sh"), nil, 0o644))
+	out, retcode = runMain(t, "verify", tmpdir)
+	assert.Equal(t, true, retcode != 0)
+	assert.ContainsSubstring(t, out, "extra      custom.sh\n"+
+		"missing    e2e-tests.sh\n"+
+		"modified   release.sh\n")
+	assert.ContainsSubstring(t, out, "scripts differ from the embedded copies: 3 file(s)")
+}
+
+func TestExecSubcommand(t *testing.T) {
+	t.Setenv(extract.HackScriptsDirEnvVar, t.TempDir())
+	t.Setenv(cli.ManualVerboseEnvVar, "true")
+
+	out, retcode := runMain(t, "exec", "microbenchmarks.sh", "-v", "--help")
+	assert.Equal(t, 0, retcode)
+	assert.Equal(t, "", out)
+
+	// the boilerplate isn't a valid script, so bash exits with a syntax error
+	out, retcode = runMain(t, "exec", "boilerplate.go.txt")
+	assert.Equal(t, 2, retcode)
+	assert.ContainsSubstring(t, out, "syntax error")
+	assert.Equal(t, false, strings.Contains(out, "exit status"))
+}
+
+func runMain(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	var buf bytes.Buffer
+	retcode := 0
+	withOptions(main.RunMain, func(ex *cli.Execution) {
+		ex.Stdin = strings.NewReader("")
+		ex.Stdout = &buf
+		ex.Stderr = &buf
+		ex.Args = args
+		ex.Exit = func(c int) {
+			retcode = c
+		}
+	})
+	return buf.String(), retcode
+}
+
 func withOptions(fn func(), options ...cli.Option) {
 	prev := cli.Options
 	cli.Options = options
@@ -28,4 +28,35 @@ func TestCallerNotAllowed(t *testing.T) {
 	if !errors.Is(err, shell.ErrCallerNotAllowed) {
 		t.Error("usage should be blocked")
 	}
+	_, err = shell.NewFixedProjectLocation("/")
+	if !errors.Is(err, shell.ErrCallerNotAllowed) {
+		t.Error("usage should be blocked")
+	}
+}
+
+func TestCallerAllowedByOption(t *testing.T) {
+	_, err := shell.NewProjectLocation("..",
+		shell.WithAllowedCallers("knative.dev/hack/foo"))
+	if err != nil {
+		t.Errorf("usage should be allowed: %v", err)
+	}
+}
+
+func TestCallerAllowedByEnv(t *testing.T) {
+	t.Setenv(shell.AllowedCallersEnvVar, "knative.dev/hack/foo")
+	_, err := shell.NewProjectLocation("..")
+	if err != nil {
+		t.Errorf("usage should be allowed: %v", err)
+	}
+}
+
+func TestCallerPolicy(t *testing.T) {
+	denied := errors.New("denied")
+	_, err := shell.NewProjectLocation("..", shell.WithCallerPolicy(
+		shell.CallerPolicyFunc(func(string) error {
+			return denied
+		})))
+	if !errors.Is(err, denied) {
+		t.Errorf("usage should be denied by the policy, got: %v", err)
+	}
 }
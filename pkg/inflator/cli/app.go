@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 
 	"knative.dev/hack/pkg/inflator/extract"
@@ -17,24 +18,37 @@ func Execute(opts []Option) Result {
 			Err:       err,
 		}
 	}
-	op := createOperation(fl, ex.Args)
+	cmd, args := route(ex.Args)
+	if fl.outputSet && cmd.name != pathCommand {
+		return Result{
+			Execution: ex,
+			Err: fmt.Errorf("%w: the %s command doesn't take the --output flag",
+				ErrInvalidOutput, cmd.name),
+		}
+	}
 	return Result{
 		Execution: ex,
-		Err:       op.Extract(ex),
+		Err:       cmd.run(ex, fl, args),
 	}
 }
 
 // ExecuteOrDie will execute the application or perform os.Exit in case of error.
 func ExecuteOrDie(opts ...Option) {
 	if r := Execute(opts); r.Err != nil {
-		r.PrintErrln(fmt.Sprintf("%v", r.Err))
+		// the script executed by exec already reported its failure
+		var ec exitCodeErr
+		if !errors.As(r.Err, &ec) {
+			r.PrintErrln(fmt.Sprintf("%v", r.Err))
+		}
 		r.Exit(retcode.Calc(r.Err))
 	}
 }
 
-func createOperation(fl *flags, argv []string) extract.Operation {
+func createOperation(ex Execution, fl *flags, scriptName string) extract.Operation {
 	return extract.Operation{
-		ScriptName: argv[0],
+		ScriptName: scriptName,
 		Verbose:    fl.verbose,
+		Layers:     ex.Layers,
+		DryRun:     fl.dryRun,
 	}
 }
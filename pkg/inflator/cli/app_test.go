@@ -2,7 +2,14 @@ package cli_test
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"knative.dev/hack/pkg/inflator/cli"
 	"knative.dev/hack/pkg/inflator/extract"
@@ -29,3 +36,186 @@ func TestExecute(t *testing.T) {
 	assert.Equal(t, outb.String(), tmpdir+"/e2e-tests.sh\n")
 	assert.Equal(t, errb.String(), "")
 }
+
+func TestExecuteReference(t *testing.T) {
+	var (
+		outb bytes.Buffer
+		errb bytes.Buffer
+	)
+
+	r := cli.Execute([]cli.Option{func(ex *cli.Execution) {
+		ex.Args = []string{"reference", "library.sh"}
+		ex.Stdout = &outb
+		ex.Stderr = &errb
+	}})
+
+	require.NoError(t, r.Err)
+	assert.ContainsSubstring(t, outb.String(), "# library.sh\n")
+	assert.ContainsSubstring(t, outb.String(), "## run_go_tool\n\nDeprecated: use go_run instead\n")
+	assert.ContainsSubstring(t, outb.String(), "  $1 - tool package for go run.\n")
+	assert.Equal(t, errb.String(), "")
+}
+
+func TestExecuteGC(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	stale := path.Join(extract.BaseDir(), "v0.0.1")
+	require.NoError(t, os.MkdirAll(stale, 0o755))
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(stale, old, old))
+	var outb bytes.Buffer
+
+	r := cli.Execute([]cli.Option{func(ex *cli.Execution) {
+		ex.Args = []string{"gc", "--max-idle=1m"}
+		ex.Stdout = &outb
+	}})
+
+	require.NoError(t, r.Err)
+	assert.Equal(t, outb.String(), stale+"\n")
+	_, err := os.Stat(stale)
+	assert.Equal(t, os.IsNotExist(err), true)
+}
+
+func TestExecuteWithLayer(t *testing.T) {
+	t.Setenv(extract.HackScriptsDirEnvVar, t.TempDir())
+	layer := fstest.MapFS{
+		"custom.sh": {Data: []byte("# Says hi.\nfunction hi() {\n  echo hi\n}\n")},
+	}
+	run := func(args ...string) string {
+		var outb bytes.Buffer
+		r := cli.Execute([]cli.Option{cli.WithLayer("downstream", layer), func(ex *cli.Execution) {
+			ex.Args = args
+			ex.Stdout = &outb
+		}})
+		require.NoError(t, r.Err)
+		return outb.String()
+	}
+
+	assert.ContainsSubstring(t, run("list"), "custom.sh                           39 ")
+	assert.ContainsSubstring(t, run("list"), " knative.dev/hack\n")
+	assert.Equal(t, run("cat", "custom.sh"), "# Says hi.\nfunction hi() {\n  echo hi\n}\n")
+	assert.ContainsSubstring(t, run("reference", "custom.sh"), "## hi\n\nSays hi.\n")
+	assert.ContainsSubstring(t, run("custom.sh"), "/custom.sh\n")
+}
+
+func TestExecuteCatMissing(t *testing.T) {
+	r := cli.Execute([]cli.Option{func(ex *cli.Execution) {
+		ex.Args = []string{"cat", "missing.sh"}
+		ex.Stdout = &bytes.Buffer{}
+	}})
+	assert.Equal(t, errors.Is(r.Err, extract.ErrUnexpected), true)
+	assert.Equal(t, errors.Is(r.Err, fs.ErrNotExist), true)
+}
+
+func TestExecuteOutput(t *testing.T) {
+	tmpdir := t.TempDir()
+	t.Setenv(extract.HackScriptsDirEnvVar, tmpdir)
+	run := func(args ...string) (string, error) {
+		var outb bytes.Buffer
+		r := cli.Execute([]cli.Option{func(ex *cli.Execution) {
+			ex.Args = args
+			ex.Stdout = &outb
+		}})
+		return outb.String(), r.Err
+	}
+
+	out, err := run("--output=json", "library.sh")
+	require.NoError(t, err)
+	var ext extract.Extraction
+	require.NoError(t, json.Unmarshal([]byte(out), &ext))
+	assert.Equal(t, ext.Root, tmpdir)
+	assert.Equal(t, ext.Script, tmpdir+"/library.sh")
+	assert.Equal(t, len(ext.Files), 10)
+	for _, f := range ext.Files {
+		assert.Equal(t, f.Status, extract.StatusWritten)
+		assert.Equal(t, len(f.Digest), 64)
+		assert.Greater(t, f.Bytes, 0)
+		assert.Equal(t, f.Layer, extract.HackLayerName)
+	}
+
+	out, err = run("-o", "json", "path", "library.sh")
+	require.NoError(t, err)
+	assert.ContainsSubstring(t, out, `"status": "up-to-date"`)
+
+	out, err = run("library.sh", "--output", "env")
+	require.NoError(t, err)
+	assert.Equal(t, out, "export KNATIVE_HACK_SCRIPTS_DIR='"+tmpdir+"'\n"+
+		"export KNATIVE_HACK_SCRIPT_PATH='"+tmpdir+"/library.sh'\n")
+
+	_, err = run("--output=yaml", "library.sh")
+	assert.Equal(t, errors.Is(err, cli.ErrInvalidOutput), true)
+
+	_, err = run("-o", "json", "list")
+	assert.Equal(t, errors.Is(err, cli.ErrInvalidOutput), true)
+}
+
+func TestExecuteDryRun(t *testing.T) {
+	tmpdir := t.TempDir()
+	t.Setenv(extract.HackScriptsDirEnvVar, tmpdir)
+	layer := cli.WithLayer("downstream", fstest.MapFS{
+		"custom.sh": {Data: []byte("echo custom\n")},
+	})
+	run := func(opts ...cli.Option) (string, string) {
+		var outb, errb bytes.Buffer
+		r := cli.Execute(append(opts, func(ex *cli.Execution) {
+			ex.Stdout = &outb
+			ex.Stderr = &errb
+		}))
+		require.NoError(t, r.Err)
+		return outb.String(), errb.String()
+	}
+	args := func(args ...string) cli.Option {
+		return func(ex *cli.Execution) {
+			ex.Args = args
+		}
+	}
+
+	run(layer, args("library.sh"))
+	out, errOut := run(args("--dry-run", "library.sh"))
+	assert.Equal(t, out, tmpdir+"/library.sh\n")
+	assert.ContainsSubstring(t, errOut, "custom.sh                      stale, would be pruned")
+	_, err := os.Stat(path.Join(tmpdir, "custom.sh"))
+	require.NoError(t, err)
+
+	run(args("library.sh"))
+	_, err = os.Stat(path.Join(tmpdir, "custom.sh"))
+	assert.Equal(t, os.IsNotExist(err), true)
+}
+
+func TestExecuteRetcode(t *testing.T) {
+	run := func(args ...string) string {
+		var outb bytes.Buffer
+		r := cli.Execute([]cli.Option{func(ex *cli.Execution) {
+			ex.Args = args
+			ex.Stdout = &outb
+		}})
+		require.NoError(t, r.Err)
+		return outb.String()
+	}
+
+	assert.ContainsSubstring(t, run("retcode", "list"),
+		" 74 knative.dev/hack/pkg/inflator/extract.ErrUnexpected\n")
+	assert.ContainsSubstring(t, run("retcode", "list"),
+		" 64 knative.dev/hack/pkg/inflator/cli.ErrInvalidOutput\n")
+	explained := run("retcode", "explain", "70")
+	assert.ContainsSubstring(t, explained,
+		"70 knative.dev/hack/pkg/inflator/extract.ErrBug: probably a bug in the code\n")
+	assert.ContainsSubstring(t, explained, "so 70 might come from such an error too")
+	assert.ContainsSubstring(t, run("retcode", "explain", "84"),
+		"84 isn't a well-known retcode")
+}
+
+func TestExecuteOrDieRetcode(t *testing.T) {
+	t.Setenv(extract.HackScriptsDirEnvVar, t.TempDir())
+	var errb bytes.Buffer
+	code := -1
+	cli.ExecuteOrDie(func(ex *cli.Execution) {
+		ex.Args = []string{"no-such-script.sh"}
+		ex.Stdout = &errb
+		ex.Stderr = &errb
+		ex.Exit = func(c int) {
+			code = c
+		}
+	})
+	assert.Equal(t, code, extract.RetcodeUnexpected)
+	assert.ContainsSubstring(t, errb.String(), "unexpected situation: open no-such-script.sh")
+}
@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"knative.dev/hack/pkg/inflator/extract"
+)
+
+const (
+	pathCommand = "path"
+	execCommand = "exec"
+)
+
+// command is a subcommand of the script tool.
+type command struct {
+	name  string
+	usage string
+	help  string
+	run   func(ex Execution, fl *flags, args []string) error
+}
+
+// commands returns the subcommands, in the order they are listed in usage.
+func commands() []command {
+	return []command{{
+		name:  "list",
+		usage: "list",
+		help:  "list the embedded scripts with their sizes and digests",
+		run:   listScripts,
+	}, {
+		name:  "cat",
+		usage: "cat library.sh",
+		help:  "print the script, without extracting it",
+		run:   catScript,
+	}, {
+		name:  pathCommand,
+		usage: "path library.sh",
+		help:  "extract the scripts, and print the path of the script",
+		run:   extractScript,
+	}, {
+		name:  execCommand,
+		usage: "exec library.sh [args...]",
+		help:  "extract the scripts, and run the script with bash",
+		run:   execScript,
+	}, {
+		name:  "verify",
+		usage: "verify <dir>",
+		help:  "report the differences between the directory and the scripts",
+		run:   verifyScripts,
+	}, {
+		name:  referenceCommand,
+		usage: "reference [library.sh...]",
+		help:  "print the documented functions of the scripts",
+		run:   printReference,
+	}, {
+		name:  retcodeCommand,
+		usage: "retcode list | explain <code>",
+		help:  "list the well-known retcodes, or explain one",
+		run:   explainRetcode,
+	}, {
+		name:  gcCommand,
+		usage: "gc [--max-age=0] [--max-idle=168h]",
+		help:  "remove the extraction directories of other versions",
+		run:   collectGarbage,
+	}}
+}
+
+// route finds the subcommand for the arguments. Without a known subcommand,
+// the arguments are handled by the path command, as in "script library.sh".
+func route(argv []string) (command, []string) {
+	for _, c := range commands() {
+		if c.name == argv[0] {
+			return c, argv[1:]
+		}
+	}
+	return command{name: pathCommand, run: extractScript}, argv
+}
+
+// exitCodeErr passes the exit code of the executed script through.
+type exitCodeErr struct {
+	code int
+}
+
+func (e exitCodeErr) Retcode() int {
+	return e.code
+}
+
+func (e exitCodeErr) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func scriptName(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", usageErr{}
+	}
+	return args[0], nil
+}
+
+func listScripts(ex Execution, _ *flags, args []string) error {
+	if len(args) != 0 {
+		return usageErr{}
+	}
+	entries, err := extract.List(ex.Layers...)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if len(ex.Layers) > 0 {
+			ex.Printf("%-30s %7d %s %s\n", e.Name, e.Size, e.Digest, e.Layer)
+			continue
+		}
+		ex.Printf("%-30s %7d %s\n", e.Name, e.Size, e.Digest)
+	}
+	return nil
+}
+
+func catScript(ex Execution, _ *flags, args []string) error {
+	name, err := scriptName(args)
+	if err != nil {
+		return err
+	}
+	bytes, err := extract.Stack(ex.Layers...).ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("%w: %w", extract.ErrUnexpected, err)
+	}
+	ex.Print(string(bytes))
+	return nil
+}
+
+func extractScript(ex Execution, fl *flags, args []string) error {
+	name, err := scriptName(args)
+	if err != nil {
+		return err
+	}
+	op := createOperation(ex, fl, name)
+	if fl.output == outputPath {
+		return op.Extract(ex)
+	}
+	ext, err := op.Run(ex)
+	if err != nil {
+		return err
+	}
+	return printExtraction(ex, fl.output, ext)
+}
+
+func execScript(ex Execution, fl *flags, args []string) error {
+	if len(args) == 0 {
+		return usageErr{}
+	}
+	op := createOperation(ex, fl, args[0])
+	scriptPath, err := op.Inflate(ex)
+	if err != nil {
+		return err
+	}
+	c := exec.Command("bash", append([]string{scriptPath}, args[1:]...)...)
+	c.Stdin = ex.Stdin
+	c.Stdout = ex.Stdout
+	c.Stderr = ex.Stderr
+	if err = c.Run(); err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			return exitCodeErr{code: ee.ExitCode()}
+		}
+		return fmt.Errorf("%w: %w", extract.ErrUnexpected, err)
+	}
+	return nil
+}
+
+func verifyScripts(ex Execution, _ *flags, args []string) error {
+	dir, err := scriptName(args)
+	if err != nil {
+		return err
+	}
+	drifts, err := extract.Verify(dir, ex.Layers...)
+	if err != nil {
+		return err
+	}
+	for _, d := range drifts {
+		ex.Printf("%-10s %s\n", d.Kind, d.Name)
+	}
+	if len(drifts) > 0 {
+		return fmt.Errorf("%w: %d file(s) in %s", extract.ErrDrift, len(drifts), dir)
+	}
+	return nil
+}
@@ -2,19 +2,28 @@ package cli
 
 import (
 	"io"
+	"io/fs"
 	"os"
+
+	"knative.dev/hack/pkg/inflator/extract"
 )
 
 // Execution is used to execute a command.
 type Execution struct {
 	Args   []string
+	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
 	Exit   func(code int)
+	// Layers hold extra scripts, stacked on top of the embedded hack scripts.
+	Layers []extract.Layer
 }
 
 // Default will set default values for the execution.
 func (e Execution) Default() Execution {
+	if e.Stdin == nil {
+		e.Stdin = os.Stdin
+	}
 	if e.Stdout == nil {
 		e.Stdout = os.Stdout
 	}
@@ -44,6 +53,15 @@ type Option func(*Execution)
 // Options to override the commandline for testing purposes.
 var Options []Option //nolint:gochecknoglobals
 
+// WithLayer registers a file system with extra scripts, on top of the
+// embedded hack scripts and the layers registered before. It allows the
+// downstream projects to build their own self-extracting script binaries.
+func WithLayer(name string, fsys fs.FS) Option {
+	return func(ex *Execution) {
+		ex.Layers = append(ex.Layers, extract.Layer{Name: name, FS: fsys})
+	}
+}
+
 // Result is a result of execution.
 type Result struct {
 	Execution
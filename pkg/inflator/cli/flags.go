@@ -1,8 +1,12 @@
 package cli
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strings"
+
+	"knative.dev/hack/pkg/retcode"
 )
 
 const (
@@ -11,28 +15,90 @@ const (
 	ManualVerboseEnvVar = "KNATIVE_HACK_SCRIPT_MANUAL_VERBOSE"
 )
 
+// ErrInvalidOutput is returned for an unknown value of the --output flag, or
+// when the flag is given to a command that doesn't print an extraction.
+var ErrInvalidOutput = errors.New("invalid output format")
+
+// RetcodeInvalidOutput is returned for ErrInvalidOutput. It's the EX_USAGE of
+// sysexits.h.
+const RetcodeInvalidOutput = 64
+
+func init() { //nolint:gochecknoinits
+	retcode.Register(retcode.Code{
+		Code: RetcodeInvalidOutput,
+		Err:  ErrInvalidOutput,
+		Name: "knative.dev/hack/pkg/inflator/cli.ErrInvalidOutput",
+		Description: "The --output flag has an unknown value, or is given " +
+			"to a command other than path. Use one of: path, json or env.",
+	})
+}
+
+// outputFormat is the format, in which the path command prints the outcome
+// of the extraction.
+type outputFormat string
+
+const (
+	// outputPath prints only the path of the script.
+	outputPath outputFormat = "path"
+	// outputJSON prints the extraction details as a JSON object.
+	outputJSON outputFormat = "json"
+	// outputEnv prints the shell exports to be evaluated.
+	outputEnv outputFormat = "env"
+)
+
 type flags struct {
 	verbose bool
+	dryRun  bool
+	output  outputFormat
+	// outputSet tells if the --output flag was given.
+	outputSet bool
 }
 
+// parseArgs strips the global flags from the arguments. The arguments after
+// the script name of the exec command are left as is, for the script.
 func parseArgs(ex *Execution) (*flags, error) {
 	f := flags{
 		verbose: isCiServer(),
+		output:  outputPath,
 	}
-	if len(ex.Args) == 0 {
-		return nil, usageErr{}
-	}
+	args := make([]string, 0, len(ex.Args))
 	for i := 0; i < len(ex.Args); i++ {
-		if ex.Args[i] == "-v" || ex.Args[i] == "--verbose" {
-			f.verbose = true
-			ex.Args = append(ex.Args[:i], ex.Args[i+1:]...)
-			i--
+		arg := ex.Args[i]
+		if len(args) == 2 && args[0] == execCommand {
+			args = append(args, ex.Args[i:]...)
+			break
 		}
-
-		if ex.Args[i] == "-h" || ex.Args[i] == "--help" {
+		switch {
+		case arg == "-v" || arg == "--verbose":
+			f.verbose = true
+		case arg == "--dry-run":
+			f.dryRun = true
+		case arg == "-h" || arg == "--help":
 			return nil, usageErr{}
+		case arg == "-o" || arg == "--output":
+			if i+1 == len(ex.Args) {
+				return nil, fmt.Errorf("%w: %s requires a value", ErrInvalidOutput, arg)
+			}
+			i++
+			f.output = outputFormat(ex.Args[i])
+			f.outputSet = true
+		case strings.HasPrefix(arg, "--output="):
+			f.output = outputFormat(strings.TrimPrefix(arg, "--output="))
+			f.outputSet = true
+		default:
+			args = append(args, arg)
 		}
 	}
+	switch f.output {
+	case outputPath, outputJSON, outputEnv:
+	default:
+		return nil, fmt.Errorf("%w: %q, want one of: %s, %s, %s",
+			ErrInvalidOutput, f.output, outputPath, outputJSON, outputEnv)
+	}
+	if len(args) == 0 {
+		return nil, usageErr{}
+	}
+	ex.Args = args
 	return &f, nil
 }
 
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"time"
+
+	"knative.dev/hack/pkg/inflator/extract"
+)
+
+const (
+	// gcCommand is the subcommand removing the stale extraction directories.
+	gcCommand = "gc"
+	// defaultMaxIdle is the default time after which the unused extraction
+	// directories are removed.
+	defaultMaxIdle = 7 * 24 * time.Hour
+)
+
+func collectGarbage(ex Execution, fl *flags, args []string) error {
+	gc := extract.GC{Verbose: fl.verbose, Layers: ex.Layers}
+	fs := flag.NewFlagSet(gcCommand, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.DurationVar(&gc.MaxAge, "max-age", 0,
+		"remove directories extracted longer ago")
+	fs.DurationVar(&gc.MaxIdle, "max-idle", defaultMaxIdle,
+		"remove directories not used for that long")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return gc.Collect(ex)
+}
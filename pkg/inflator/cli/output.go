@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+
+	"knative.dev/hack/pkg/inflator/extract"
+)
+
+// ScriptPathEnvVar is the variable exported by the env output format, holding
+// the path of the requested script.
+const ScriptPathEnvVar = "KNATIVE_HACK_SCRIPT_PATH"
+
+func printExtraction(ex Execution, format outputFormat, ext extract.Extraction) error {
+	if format == outputEnv {
+		ex.Printf("export %s=%s\n", extract.HackScriptsDirEnvVar, shellQuote(ext.Root))
+		ex.Printf("export %s=%s\n", ScriptPathEnvVar, shellQuote(ext.Script))
+		return nil
+	}
+	enc := json.NewEncoder(ex.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ext)
+}
+
+// shellQuote puts the string in single quotes, so it's safe to eval.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
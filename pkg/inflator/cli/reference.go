@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"knative.dev/hack/pkg/inflator/extract"
+	"knative.dev/hack/pkg/introspect"
+)
+
+// referenceCommand is the subcommand printing the functions of the scripts.
+const referenceCommand = "reference"
+
+// printReference prints the documented functions of the given scripts, or of
+// all the scripts if none are given.
+func printReference(ex Execution, _ *flags, names []string) error {
+	c, err := introspect.Load(extract.Stack(ex.Layers...))
+	if err != nil {
+		return err
+	}
+	scripts := c.Scripts
+	if len(names) > 0 {
+		scripts = make([]introspect.Script, 0, len(names))
+		for _, name := range names {
+			sc, ok := c.Script(name)
+			if !ok {
+				return fmt.Errorf("%w: %s", introspect.ErrUnknownScript, name)
+			}
+			scripts = append(scripts, sc)
+		}
+	}
+	for _, sc := range scripts {
+		ex.Printf("# %s\n", sc.Name)
+		if len(sc.Sources) > 0 {
+			ex.Printf("\nSources: %s\n", strings.Join(sc.Sources, ", "))
+		}
+		for _, fn := range sc.Functions {
+			ex.Printf("\n## %s\n", fn.Name)
+			if fn.Deprecated {
+				ex.Printf("\nDeprecated: %s\n", fn.Deprecation)
+			}
+			if fn.Doc != "" {
+				ex.Printf("\n%s\n", fn.Doc)
+			}
+			if len(fn.Parameters) > 0 {
+				ex.Println()
+			}
+			for _, p := range fn.Parameters {
+				if p.Name == "" {
+					ex.Printf("  %s\n", p.Description)
+					continue
+				}
+				ex.Printf("  %s - %s\n", p.Name, p.Description)
+			}
+		}
+		ex.Println()
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"strconv"
+
+	"knative.dev/hack/pkg/retcode"
+)
+
+// retcodeCommand is the subcommand explaining the well-known retcodes.
+const retcodeCommand = "retcode"
+
+func explainRetcode(ex Execution, _ *flags, args []string) error {
+	switch {
+	case len(args) == 1 && args[0] == "list":
+		for _, c := range retcode.Codes() {
+			ex.Printf("%3d %s\n", c.Code, c.Name)
+		}
+		return nil
+	case len(args) == 2 && args[0] == "explain":
+		code, err := strconv.Atoi(args[1])
+		if err != nil {
+			return usageErr{}
+		}
+		c, ok := retcode.Lookup(code)
+		if !ok {
+			ex.Printf("%d isn't a well-known retcode. It was probably calculated "+
+				"from the error message.\n", code)
+			return nil
+		}
+		ex.Printf("%d %s: %v\n\n%s\n", c.Code, c.Name, c.Err, c.Description)
+		if code >= retcode.LowerBound && code < retcode.UpperBound {
+			ex.Printf("\nThe retcodes of the other errors are calculated from the error "+
+				"message, within %d and %d, so %d might come from such an error too.\n",
+				retcode.LowerBound, retcode.UpperBound-1, code)
+		}
+		return nil
+	default:
+		return usageErr{}
+	}
+}
@@ -1,5 +1,10 @@
 package cli
 
+import (
+	"fmt"
+	"strings"
+)
+
 type usageErr struct{}
 
 func (u usageErr) Retcode() int {
@@ -7,6 +12,10 @@ func (u usageErr) Retcode() int {
 }
 
 func (u usageErr) Error() string {
+	var cmds strings.Builder
+	for _, c := range commands() {
+		_, _ = fmt.Fprintf(&cmds, "\t%-36s %s\n", c.usage, c.help)
+	}
 	return `Hacks as Go self-extracting binary
 
 Will extract Hack scripts to a temporary directory, and provide a source
@@ -17,9 +26,14 @@ source "$(go run knative.dev/hack/cmd/script@latest library.sh)"
 
 Usage:
 	script [flags] library.sh
+	script [flags] <command> [args...]
 
+Commands:
+` + cmds.String() + `
 Flags:
-	-h, --help      help
-	-v, --verbose   verbose output
+	-h, --help          help
+	-v, --verbose       verbose output
+	-o, --output=path   output of the path command: path, json or env
+	--dry-run           list the stale files, instead of pruning them
 `
 }
@@ -3,6 +3,8 @@ package extract
 import (
 	"errors"
 	"fmt"
+
+	"knative.dev/hack/pkg/retcode"
 )
 
 var (
@@ -13,6 +15,44 @@ var (
 	ErrUnexpected = errors.New("unexpected situation")
 )
 
+// The retcodes follow the sysexits.h conventions.
+const (
+	// RetcodeDrift is returned when the scripts differ from the embedded
+	// ones, see ErrDrift. It's the EX_DATAERR of sysexits.h.
+	RetcodeDrift = 65
+	// RetcodeBug is returned for ErrBug. It's the EX_SOFTWARE of sysexits.h.
+	RetcodeBug = 70
+	// RetcodeUnexpected is returned for ErrUnexpected. It's the EX_IOERR of
+	// sysexits.h, as the unexpected situations are mostly I/O failures.
+	RetcodeUnexpected = 74
+)
+
+func init() { //nolint:gochecknoinits
+	retcode.Register(retcode.Code{
+		Code: RetcodeDrift,
+		Err:  ErrDrift,
+		Name: "knative.dev/hack/pkg/inflator/extract.ErrDrift",
+		Description: "The directory holds scripts that differ from the embedded ones. " +
+			"Extract the scripts again, or remove the modified files.",
+	})
+	retcode.Register(retcode.Code{
+		Code: RetcodeBug,
+		Err:  ErrBug,
+		Name: "knative.dev/hack/pkg/inflator/extract.ErrBug",
+		Description: "The embedded scripts couldn't be read. This is probably a bug, " +
+			"please report it at https://github.com/knative/hack/issues.",
+	})
+	retcode.Register(retcode.Code{
+		Code: RetcodeUnexpected,
+		Err:  ErrUnexpected,
+		Name: "knative.dev/hack/pkg/inflator/extract.ErrUnexpected",
+		Description: "The scripts couldn't be extracted, or the requested script doesn't " +
+			"exist. Check the script name, and the extraction directory permissions.",
+	})
+}
+
+// wrapErr marks the error with the target, keeping the original error in the
+// chain, so the retcode it may carry isn't lost.
 func wrapErr(err error, target error) error {
 	if err == nil {
 		return nil
@@ -20,5 +60,5 @@ func wrapErr(err error, target error) error {
 	if errors.Is(err, target) {
 		return err
 	}
-	return fmt.Errorf("%w: %v", target, err)
+	return fmt.Errorf("%w: %w", target, err)
 }
@@ -2,12 +2,8 @@ package extract
 
 import (
 	"io/fs"
-	"math"
 	"os"
 	"path"
-	"strings"
-
-	"knative.dev/hack"
 )
 
 const (
@@ -36,44 +32,122 @@ type Operation struct {
 	ScriptName string
 	// Verbose will print more information.
 	Verbose bool
+	// Layers are stacked on top of the embedded hack scripts. The files of the
+	// later layers override the files of the earlier ones.
+	Layers []Layer
+	// DryRun lists the stale files, extracted before but not in the library
+	// anymore, instead of removing them.
+	DryRun bool
 }
 
 // Extract will extract a script from the library to a temporary directory and
 // provide the file path to it.
 func (o Operation) Extract(prtr Printer) error {
-	l := logger{o.Verbose, prtr}
+	scriptPath, err := o.Inflate(prtr)
+	if err != nil {
+		return err
+	}
+	prtr.Println(scriptPath)
+	return nil
+}
+
+// Inflate will extract a script from the library to a temporary directory and
+// return the file path to it.
+func (o Operation) Inflate(prtr Printer) (string, error) {
+	ext, err := o.Run(prtr)
+	return ext.Script, err
+}
+
+// Run will extract the scripts from the library to a temporary directory, and
+// describe the outcome for each of the files.
+func (o Operation) Run(prtr Printer) (Extraction, error) {
+	var ext Extraction
+	scripts := Stack(o.Layers...)
+	l := logger{verbose: o.Verbose, Printer: prtr, files: &ext.Files, layered: scripts.layered()}
 	hackRootDir := os.Getenv(HackScriptsDirEnvVar)
-	if f, err := hack.Scripts.Open(o.ScriptName); err != nil {
-		return wrapErr(err, ErrUnexpected)
+	if f, err := scripts.Open(o.ScriptName); err != nil {
+		return ext, wrapErr(err, ErrUnexpected)
 	} else if err = f.Close(); err != nil {
-		return wrapErr(err, ErrUnexpected)
+		return ext, wrapErr(err, ErrUnexpected)
 	}
 	if hackRootDir == "" {
-		hackRootDir = path.Join(os.TempDir(), "knative", "hack", "scripts")
+		hackRootDir = DefaultDir()
+		if scripts.layered() {
+			hackRootDir = path.Join(BaseDir(), contentVersion(scripts))
+		}
 	}
 	l.debugf("Extracting hack scripts to directory: %s", hackRootDir)
-	if err := copyDir(l, hack.Scripts, hackRootDir, "."); err != nil {
-		return err
+	if err := os.MkdirAll(hackRootDir, PermOwnerWrite|PermAllExecutable|0o444); err != nil {
+		return ext, wrapErr(err, ErrUnexpected)
+	}
+	unlock, err := lockDir(hackRootDir)
+	if err != nil {
+		return ext, wrapErr(err, ErrUnexpected)
+	}
+	defer unlock()
+	m, err := readManifest(hackRootDir)
+	if err != nil {
+		return ext, wrapErr(err, ErrUnexpected)
+	}
+	previous := m.clone()
+	if err = copyDir(l, scripts, hackRootDir, ".", m); err != nil {
+		return ext, err
+	}
+	if err = o.prune(l, hackRootDir, previous, m); err != nil {
+		return ext, err
+	}
+	// the manifest is always written, to mark the directory as recently used
+	if err = m.write(hackRootDir); err != nil {
+		return ext, wrapErr(err, ErrUnexpected)
+	}
+	ext.Root = hackRootDir
+	ext.Script = path.Join(hackRootDir, o.ScriptName)
+	return ext, nil
+}
+
+// prune removes the files listed in the previous manifest, that weren't
+// extracted this time. The files not listed, like the ones added by the user,
+// are left alone.
+func (o Operation) prune(l logger, destRootDir string, previous, current manifest) error {
+	extracted := make(map[string]bool, len(*l.files))
+	for _, rec := range *l.files {
+		extracted[rec.Path] = true
+	}
+	for _, name := range previous.names() {
+		if extracted[name] || !fs.ValidPath(name) {
+			continue
+		}
+		rec := FileRecord{Path: name, Status: StatusStale, Digest: previous[name]}
+		if !o.DryRun {
+			err := os.Remove(path.Join(destRootDir, name))
+			if err != nil && !os.IsNotExist(err) {
+				return wrapErr(err, ErrUnexpected)
+			}
+			delete(current, name)
+			rec.Status = StatusPruned
+		}
+		l.file(rec)
 	}
-	scriptPath := path.Join(hackRootDir, o.ScriptName)
-	l.Println(scriptPath)
 	return nil
 }
 
-func copyDir(l logger, inputFS fs.ReadDirFS, destRootDir, dir string) error {
+func copyDir(l logger, inputFS *Overlay, destRootDir, dir string, m manifest) error {
 	return wrapErr(fs.WalkDir(inputFS, dir, func(filePath string, dirEntry fs.DirEntry, err error) error {
 		if err != nil {
 			return wrapErr(err, ErrBug)
 		}
-		return copyFile(l, inputFS, destRootDir, filePath, dirEntry)
+		return copyFile(l, inputFS, destRootDir, filePath, dirEntry, m)
 	}), ErrUnexpected)
 }
 
+// copyFile extracts the file, unless the manifest and the file on disk both
+// match the digest of the embedded content.
 func copyFile(
 	l logger,
-	inputFS fs.ReadDirFS,
+	inputFS *Overlay,
 	destRootDir, filePath string,
 	dirEntry fs.DirEntry,
+	m manifest,
 ) error {
 	inputFI, err := dirEntry.Info()
 	if err != nil {
@@ -93,44 +167,35 @@ func copyFile(
 	}
 
 	var (
-		inputCS checksum
-		destCS  checksum
-		destFI  fs.FileInfo
-		bytes   []byte
+		bytes      []byte
+		destDigest string
 	)
-	inputCS = asChecksum(inputFI)
-	if destFI, err = os.Stat(destPath); err != nil {
-		if !os.IsNotExist(err) {
-			return wrapErr(err, ErrUnexpected)
-		}
-	} else {
-		destCS = asChecksum(destFI)
+	if bytes, err = fs.ReadFile(inputFS, filePath); err != nil {
+		return wrapErr(err, ErrBug)
 	}
-	if inputCS == destCS {
-		l.debugf("%-30s up-to-date", filePath)
-		return nil
+	rec := FileRecord{
+		Path:   filePath,
+		Status: StatusUpToDate,
+		Bytes:  int64(len(bytes)),
+		Digest: digest(bytes),
 	}
-	if bytes, err = fs.ReadFile(inputFS, filePath); err != nil {
+	if rec.Layer, err = inputFS.Layer(filePath); err != nil {
 		return wrapErr(err, ErrBug)
 	}
-	if err = os.WriteFile(destPath, bytes, perm); err != nil {
+	if m[filePath] == rec.Digest {
+		if destDigest, err = fileDigest(destPath); err != nil {
+			return wrapErr(err, ErrUnexpected)
+		}
+		if destDigest == rec.Digest {
+			l.file(rec)
+			return nil
+		}
+	}
+	if err = writeFileAtomic(destPath, bytes, perm); err != nil {
 		return wrapErr(err, ErrUnexpected)
 	}
-
-	sizeKB := int(inputFI.Size() / 1024)
-	size5k := int(math.Ceil(float64(sizeKB) / 5))
-	l.debugf("%-30s %3d KiB %s", filePath, sizeKB, strings.Repeat("+", size5k))
+	m[filePath] = rec.Digest
+	rec.Status = StatusWritten
+	l.file(rec)
 	return nil
 }
-
-func asChecksum(f fs.FileInfo) checksum {
-	return checksum{
-		exists: true,
-		size:   f.Size(),
-	}
-}
-
-type checksum struct {
-	exists bool
-	size   int64
-}
@@ -2,10 +2,19 @@ package extract_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"os"
+	"path"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"knative.dev/hack"
 
 	"knative.dev/hack/pkg/inflator/extract"
 	"knative.dev/hack/pkg/utest/assert"
@@ -59,6 +68,214 @@ func TestExtract(t *testing.T) {
 `, errOut)
 }
 
+func TestExtractSameSizeChange(t *testing.T) {
+	tmpdir := t.TempDir()
+	t.Setenv(extract.HackScriptsDirEnvVar, tmpdir)
+	op := extract.Operation{ScriptName: "library.sh"}
+	require.NoError(t, op.Extract(&testPrinter{}))
+
+	libPath := path.Join(tmpdir, "library.sh")
+	original, err := os.ReadFile(libPath)
+	require.NoError(t, err)
+	tampered := bytes.Replace(original, []byte("#!"), []byte("##"), 1)
+	require.NoError(t, os.WriteFile(libPath, tampered, 0o644))
+
+	require.NoError(t, op.Extract(&testPrinter{}))
+	restored, err := os.ReadFile(libPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(original), string(restored))
+
+	manifest, err := os.ReadFile(path.Join(tmpdir, extract.ManifestFileName))
+	require.NoError(t, err)
+	embedded, err := hack.Scripts.ReadFile("library.sh")
+	require.NoError(t, err)
+	sum := sha256.Sum256(embedded)
+	assert.ContainsSubstring(t, string(manifest), hex.EncodeToString(sum[:])+"  library.sh\n")
+}
+
+func TestExtractConcurrently(t *testing.T) {
+	tmpdir := t.TempDir()
+	t.Setenv(extract.HackScriptsDirEnvVar, tmpdir)
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			op := extract.Operation{ScriptName: "e2e-tests.sh"}
+			errs[i] = op.Extract(&testPrinter{})
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	entries, err := os.ReadDir(tmpdir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.Equal(t, false, strings.HasSuffix(entry.Name(), ".tmp"))
+	}
+}
+
+func TestExtractVersionedDir(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv(extract.HackScriptsDirEnvVar, "")
+	op := extract.Operation{ScriptName: "library.sh"}
+	prtr := &testPrinter{}
+	require.NoError(t, op.Extract(prtr))
+	version := extract.Version()
+	assert.Equal(t, true, version != "")
+	assert.Equal(t, path.Join(extract.BaseDir(), version, "library.sh")+"\n", prtr.out.String())
+}
+
+func TestGC(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	now := time.Now()
+	day := 24 * time.Hour
+	dirs := map[string]struct{ extracted, used time.Time }{
+		"v0.1.0":          {now.Add(-30 * day), now.Add(-10 * day)},
+		"v0.2.0":          {now.Add(-30 * day), now.Add(-time.Hour)},
+		"v0.3.0":          {now.Add(-2 * day), now.Add(-time.Hour)},
+		extract.Version(): {now.Add(-30 * day), now.Add(-10 * day)},
+	}
+	for name, times := range dirs {
+		dir := path.Join(extract.BaseDir(), name)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		for file, mtime := range map[string]time.Time{
+			extract.LockFileName:     times.extracted,
+			extract.ManifestFileName: times.used,
+		} {
+			fp := path.Join(dir, file)
+			require.NoError(t, os.WriteFile(fp, nil, 0o644))
+			require.NoError(t, os.Chtimes(fp, mtime, mtime))
+		}
+	}
+
+	prtr := &testPrinter{}
+	gc := extract.GC{MaxAge: 14 * day, MaxIdle: 7 * day, Now: func() time.Time { return now }}
+	require.NoError(t, gc.Collect(prtr))
+	assert.Equal(t, path.Join(extract.BaseDir(), "v0.1.0")+"\n"+
+		path.Join(extract.BaseDir(), "v0.2.0")+"\n", prtr.out.String())
+
+	for name, want := range map[string]bool{
+		"v0.1.0": false, "v0.2.0": false, "v0.3.0": true, extract.Version(): true,
+	} {
+		_, err := os.Stat(path.Join(extract.BaseDir(), name))
+		assert.Equal(t, want, err == nil, name)
+	}
+}
+
+func TestGCKeepsLayeredDir(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv(extract.HackScriptsDirEnvVar, "")
+	layers := []extract.Layer{{
+		Name: "overlay",
+		FS:   fstest.MapFS{"custom.sh": {Data: []byte("echo custom\n")}},
+	}}
+	op := extract.Operation{ScriptName: "custom.sh", Layers: layers}
+	ext, err := op.Run(&testPrinter{})
+	require.NoError(t, err)
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	for _, file := range []string{extract.LockFileName, extract.ManifestFileName} {
+		require.NoError(t, os.Chtimes(path.Join(ext.Root, file), old, old))
+	}
+
+	gc := extract.GC{MaxAge: time.Hour, Layers: layers}
+	require.NoError(t, gc.Collect(&testPrinter{}))
+	_, err = os.Stat(ext.Root)
+	require.NoError(t, err)
+
+	// without the layers, the directory belongs to another binary
+	gc.Layers = nil
+	require.NoError(t, gc.Collect(&testPrinter{}))
+	_, err = os.Stat(ext.Root)
+	assert.Equal(t, true, os.IsNotExist(err))
+}
+
+func TestExtractLayers(t *testing.T) {
+	tmpdir := t.TempDir()
+	t.Setenv(extract.HackScriptsDirEnvVar, tmpdir)
+	op := extract.Operation{
+		ScriptName: "custom.sh",
+		Verbose:    true,
+		Layers: []extract.Layer{{
+			Name: "first",
+			FS: fstest.MapFS{
+				"custom.sh": {Data: []byte("echo first\n")},
+			},
+		}, {
+			Name: "second",
+			FS: fstest.MapFS{
+				"custom.sh":  {Data: []byte("echo second\n")},
+				"release.sh": {Data: []byte("echo release\n")},
+			},
+		}},
+	}
+	prtr := &testPrinter{}
+	require.NoError(t, op.Extract(prtr))
+	assert.Equal(t, tmpdir+"/custom.sh\n", prtr.out.String())
+	errOut := prtr.err.String()
+	assert.ContainsSubstring(t, errOut, "custom.sh                        0 KiB  from second\n")
+	assert.ContainsSubstring(t, errOut, "release.sh                       0 KiB  from second\n")
+	assert.ContainsSubstring(t, errOut, " from knative.dev/hack\n")
+
+	for name, want := range map[string]string{
+		"custom.sh":  "echo second\n",
+		"release.sh": "echo release\n",
+	} {
+		got, err := os.ReadFile(path.Join(tmpdir, name))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(got))
+	}
+	library, err := os.ReadFile(path.Join(tmpdir, "library.sh"))
+	require.NoError(t, err)
+	embedded, err := hack.Scripts.ReadFile("library.sh")
+	require.NoError(t, err)
+	assert.Equal(t, string(embedded), string(library))
+
+	drifts, err := extract.Verify(tmpdir, op.Layers...)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(drifts))
+}
+
+func TestExtractPrunesStaleFiles(t *testing.T) {
+	tmpdir := t.TempDir()
+	t.Setenv(extract.HackScriptsDirEnvVar, tmpdir)
+	op := extract.Operation{
+		ScriptName: "library.sh",
+		Layers: []extract.Layer{{
+			Name: "old",
+			FS:   fstest.MapFS{"old.sh": {Data: []byte("echo old\n")}},
+		}},
+	}
+	require.NoError(t, op.Extract(&testPrinter{}))
+	require.NoError(t, os.WriteFile(path.Join(tmpdir, "mine.sh"), nil, 0o644))
+
+	op.Layers = nil
+	op.DryRun = true
+	prtr := &testPrinter{}
+	ext, err := op.Run(prtr)
+	require.NoError(t, err)
+	assert.Equal(t, "[hack] old.sh                         stale, would be pruned\n", prtr.err.String())
+	assert.Equal(t, extract.StatusStale, ext.Files[len(ext.Files)-1].Status)
+	_, err = os.Stat(path.Join(tmpdir, "old.sh"))
+	require.NoError(t, err)
+
+	op.DryRun = false
+	ext, err = op.Run(&testPrinter{})
+	require.NoError(t, err)
+	last := ext.Files[len(ext.Files)-1]
+	assert.Equal(t, "old.sh", last.Path)
+	assert.Equal(t, extract.StatusPruned, last.Status)
+	_, err = os.Stat(path.Join(tmpdir, "old.sh"))
+	assert.Equal(t, true, os.IsNotExist(err))
+	_, err = os.Stat(path.Join(tmpdir, "mine.sh"))
+	require.NoError(t, err)
+	manifest, err := os.ReadFile(path.Join(tmpdir, extract.ManifestFileName))
+	require.NoError(t, err)
+	assert.Equal(t, false, strings.Contains(string(manifest), "old.sh"))
+}
+
 func standarizeErrOut(errOut string, tmpdir string) string {
 	errOut = strings.ReplaceAll(errOut, tmpdir, "/tmp/x")
 	re := regexp.MustCompile(`\s+\d+ (?:Ki)?B \+*`)
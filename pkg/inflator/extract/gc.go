@@ -0,0 +1,130 @@
+package extract
+
+import (
+	"errors"
+	"os"
+	"path"
+	"time"
+)
+
+// GC removes the stale extraction directories, of other versions, from the
+// BaseDir. The directories of the current version, and of the current
+// content of the Layers, are kept. The time of the first extraction is taken from the lock file, and
+// the time of the last use from the manifest, which is rewritten by every
+// extraction.
+type GC struct {
+	// MaxAge removes the directories extracted longer ago. Zero disables
+	// the check.
+	MaxAge time.Duration
+	// MaxIdle removes the directories not used for that long. Zero disables
+	// the check.
+	MaxIdle time.Duration
+	// Verbose will print more information.
+	Verbose bool
+	// Layers are the ones given to the Operation, which extracts the scripts
+	// to a directory keyed by their content.
+	Layers []Layer
+	// Now returns the current time, defaults to time.Now.
+	Now func() time.Time
+}
+
+// Collect removes the stale extraction directories, and prints their paths.
+func (g GC) Collect(prtr Printer) error {
+	l := logger{verbose: g.Verbose, Printer: prtr}
+	now := time.Now
+	if g.Now != nil {
+		now = g.Now
+	}
+	base := BaseDir()
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return wrapErr(err, ErrUnexpected)
+	}
+	current := map[string]bool{Version(): true}
+	if scripts := Stack(g.Layers...); scripts.layered() {
+		current[contentVersion(scripts)] = true
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || current[entry.Name()] {
+			continue
+		}
+		if err = g.collect(l, path.Join(base, entry.Name()), now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collect removes the directory, if it's stale. The directory is locked, as
+// an extraction might be in progress, and checked again under the lock.
+func (g GC) collect(l logger, dir string, now time.Time) error {
+	name := path.Base(dir)
+	reason, err := g.stale(dir, now)
+	if err != nil || reason == "" {
+		l.debugf("%-30s kept", name)
+		return err
+	}
+	dirFI, err := os.Stat(dir)
+	if err != nil {
+		return wrapErr(err, ErrUnexpected)
+	}
+	lockPath := path.Join(dir, LockFileName)
+	_, lockErr := os.Stat(lockPath)
+	unlock, locked, err := tryLockDir(dir)
+	if errors.Is(err, errLockRemoved) {
+		return nil
+	}
+	if err != nil {
+		return wrapErr(err, ErrUnexpected)
+	}
+	if !locked {
+		l.debugf("%-30s in use, skipped", name)
+		return nil
+	}
+	defer unlock()
+	if os.IsNotExist(lockErr) {
+		// the lock file, just created, stands for the time of the extraction
+		if err = os.Chtimes(lockPath, dirFI.ModTime(), dirFI.ModTime()); err != nil {
+			return wrapErr(err, ErrUnexpected)
+		}
+	}
+	if reason, err = g.stale(dir, now); err != nil || reason == "" {
+		l.debugf("%-30s kept", name)
+		return err
+	}
+	l.debugf("%-30s %s", name, reason)
+	if err = os.RemoveAll(dir); err != nil {
+		return wrapErr(err, ErrUnexpected)
+	}
+	l.Println(dir)
+	return nil
+}
+
+// stale returns the reason to remove the directory, or an empty string if it
+// should be kept.
+func (g GC) stale(dir string, now time.Time) (string, error) {
+	dirFI, err := os.Stat(dir)
+	if err != nil {
+		return "", wrapErr(err, ErrUnexpected)
+	}
+	extracted := modTime(path.Join(dir, LockFileName), dirFI.ModTime())
+	used := modTime(path.Join(dir, ManifestFileName), extracted)
+	if g.MaxAge > 0 && now.Sub(extracted) > g.MaxAge {
+		return "extracted " + now.Sub(extracted).Round(time.Second).String() + " ago", nil
+	}
+	if g.MaxIdle > 0 && now.Sub(used) > g.MaxIdle {
+		return "unused for " + now.Sub(used).Round(time.Second).String(), nil
+	}
+	return "", nil
+}
+
+func modTime(filePath string, fallback time.Time) time.Time {
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return fallback
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+package extract_test
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"knative.dev/hack/pkg/inflator/extract"
+	"knative.dev/hack/pkg/utest/assert"
+	"knative.dev/hack/pkg/utest/require"
+)
+
+func TestGCSkipsLockedDir(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	dir := path.Join(extract.BaseDir(), "v0.1.0")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	lockPath := path.Join(dir, extract.LockFileName)
+	require.NoError(t, os.WriteFile(lockPath, nil, 0o644))
+	require.NoError(t, os.Chtimes(lockPath, old, old))
+
+	// an extraction in progress holds the lock
+	f, err := os.Open(lockPath)
+	require.NoError(t, err)
+	require.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_EX))
+
+	prtr := &testPrinter{}
+	gc := extract.GC{MaxAge: time.Hour}
+	require.NoError(t, gc.Collect(prtr))
+	assert.Equal(t, "", prtr.out.String())
+	_, err = os.Stat(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, f.Close())
+	require.NoError(t, gc.Collect(prtr))
+	assert.Equal(t, dir+"\n", prtr.out.String())
+	_, err = os.Stat(dir)
+	assert.Equal(t, true, os.IsNotExist(err))
+}
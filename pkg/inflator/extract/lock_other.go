@@ -0,0 +1,19 @@
+//go:build !unix
+
+package extract
+
+import "os"
+
+// lockFile is a no-op on platforms without flock. The extraction is still
+// safe from half-written files, as they are renamed into place.
+func lockFile(*os.File) error {
+	return nil
+}
+
+func tryLockFile(*os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
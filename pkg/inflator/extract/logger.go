@@ -1,8 +1,17 @@
 package extract
 
+import (
+	"math"
+	"strings"
+)
+
 type logger struct {
 	verbose bool
 	Printer
+	// files collects the records of the extracted files
+	files *[]FileRecord
+	// layered tells if the layer of each file should be printed
+	layered bool
 }
 
 func (l logger) debugf(format string, i ...interface{}) {
@@ -10,3 +19,31 @@ func (l logger) debugf(format string, i ...interface{}) {
 		l.PrintErrf("[hack] "+format+"\n", i...)
 	}
 }
+
+// file records the outcome of extracting a file, and prints it in the verbose
+// mode.
+func (l logger) file(rec FileRecord) {
+	if l.files != nil {
+		*l.files = append(*l.files, rec)
+	}
+	var layer string
+	if l.layered {
+		layer = " from " + rec.Layer
+	}
+	switch rec.Status {
+	case StatusUpToDate:
+		l.debugf("%-30s up-to-date%s", rec.Path, layer)
+		return
+	case StatusPruned:
+		l.debugf("%-30s pruned", rec.Path)
+		return
+	case StatusStale:
+		// listed even without the verbose mode, as it's what the dry-run is for
+		l.PrintErrf("[hack] %-30s stale, would be pruned\n", rec.Path)
+		return
+	case StatusWritten:
+	}
+	sizeKB := int(rec.Bytes / 1024)
+	size5k := int(math.Ceil(float64(sizeKB) / 5))
+	l.debugf("%-30s %3d KiB %s%s", rec.Path, sizeKB, strings.Repeat("+", size5k), layer)
+}
@@ -0,0 +1,187 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// ManifestFileName is the name of the file, in the extraction directory,
+	// holding the SHA-256 digests of the extracted files. It uses the format
+	// of the sha256sum tool, so it can be verified with "sha256sum -c".
+	ManifestFileName = ".manifest.sha256"
+	// LockFileName is the name of the file, in the extraction directory, used
+	// to serialize concurrent extractions.
+	LockFileName = ".lock"
+)
+
+// manifest maps the paths of the extracted files to their SHA-256 digests.
+type manifest map[string]string
+
+func digest(bytes []byte) string {
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileDigest returns the digest of a file, or an empty string if the file
+// doesn't exist.
+func fileDigest(filePath string) (string, error) {
+	bytes, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return digest(bytes), nil
+}
+
+func readManifest(dir string) (manifest, error) {
+	m := manifest{}
+	bs, err := os.ReadFile(path.Join(dir, ManifestFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+	sc := bufio.NewScanner(bytes.NewReader(bs))
+	for sc.Scan() {
+		sum, name, ok := strings.Cut(sc.Text(), "  ")
+		if !ok {
+			// a corrupted manifest causes all files to be extracted again
+			return manifest{}, nil
+		}
+		m[name] = sum
+	}
+	return m, sc.Err()
+}
+
+func (m manifest) clone() manifest {
+	c := make(manifest, len(m))
+	for name, sum := range m {
+		c[name] = sum
+	}
+	return c
+}
+
+// names returns the sorted paths of the files.
+func (m manifest) names() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m manifest) write(dir string) error {
+	var buf bytes.Buffer
+	for _, name := range m.names() {
+		_, _ = fmt.Fprintf(&buf, "%s  %s\n", m[name], name)
+	}
+	return writeFileAtomic(path.Join(dir, ManifestFileName), buf.Bytes(), 0o644)
+}
+
+// writeFileAtomic writes to a temporary file, in the same directory, and
+// renames it into place, so readers never see a partially written file.
+func writeFileAtomic(filePath string, bytes []byte, perm fs.FileMode) error {
+	dir, name := path.Split(filePath)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() {
+		_ = os.Remove(tmp)
+	}()
+	if _, err = f.Write(bytes); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filePath)
+}
+
+// lockDir takes the advisory lock of the extraction directory. The returned
+// function releases it. If the directory was removed by GC in the meantime,
+// it's created again.
+func lockDir(dir string) (func(), error) {
+	for {
+		unlock, _, err := openLock(dir, lockFile)
+		if err == nil || !errors.Is(err, errLockRemoved) {
+			return unlock, err
+		}
+		if err = os.MkdirAll(dir, PermOwnerWrite|PermAllExecutable|0o444); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// tryLockDir takes the advisory lock of the extraction directory, if it's
+// available right away.
+func tryLockDir(dir string) (func(), bool, error) {
+	return openLock(dir, func(f *os.File) error {
+		locked, err := tryLockFile(f)
+		if err == nil && !locked {
+			return errLockHeld
+		}
+		return err
+	})
+}
+
+var (
+	errLockHeld    = errors.New("lock held")
+	errLockRemoved = errors.New("lock removed")
+)
+
+func openLock(dir string, lock func(*os.File) error) (func(), bool, error) {
+	lockPath := path.Join(dir, LockFileName)
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = errLockRemoved
+		}
+		return nil, false, err
+	}
+	if err = lock(f); err != nil {
+		_ = f.Close()
+		if errors.Is(err, errLockHeld) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	unlock := func() {
+		_ = unlockFile(f)
+		_ = f.Close()
+	}
+	// the lock of a removed file doesn't guard anything
+	lockedFI, err := f.Stat()
+	if err != nil {
+		unlock()
+		return nil, false, err
+	}
+	if fi, err := os.Stat(lockPath); err != nil || !os.SameFile(fi, lockedFI) {
+		unlock()
+		return nil, false, errLockRemoved
+	}
+	return unlock, true, nil
+}
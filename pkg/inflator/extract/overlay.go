@@ -0,0 +1,106 @@
+package extract
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+
+	"knative.dev/hack"
+)
+
+// HackLayerName is the name of the layer holding the embedded hack scripts.
+const HackLayerName = "knative.dev/hack"
+
+// Layer is a named file system with scripts, stacked on top of the embedded
+// hack scripts.
+type Layer struct {
+	Name string
+	FS   fs.FS
+}
+
+// Overlay is a read-only file system merging the layers. The files of the
+// later layers override the files of the earlier ones.
+type Overlay struct {
+	layers []Layer
+}
+
+// Stack returns the overlay of the embedded hack scripts, with the given
+// layers on top of them.
+func Stack(layers ...Layer) *Overlay {
+	all := make([]Layer, 0, len(layers)+1)
+	all = append(all, Layer{Name: HackLayerName, FS: hack.Scripts})
+	return &Overlay{layers: append(all, layers...)}
+}
+
+// Open opens the file from the topmost layer having it.
+func (o *Overlay) Open(name string) (fs.File, error) {
+	_, f, err := o.open(name)
+	return f, err
+}
+
+// Layer returns the name of the topmost layer having the file.
+func (o *Overlay) Layer(name string) (string, error) {
+	l, f, err := o.open(name)
+	if err != nil {
+		return "", err
+	}
+	return l.Name, f.Close()
+}
+
+func (o *Overlay) open(name string) (Layer, fs.File, error) {
+	for i := len(o.layers) - 1; i >= 0; i-- {
+		f, err := o.layers[i].FS.Open(name)
+		if err == nil {
+			return o.layers[i], f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return Layer{}, nil, err
+		}
+	}
+	return Layer{}, nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadFile reads the file from the topmost layer having it.
+func (o *Overlay) ReadFile(name string) ([]byte, error) {
+	l, f, err := o.open(name)
+	if err != nil {
+		return nil, err
+	}
+	_ = f.Close()
+	return fs.ReadFile(l.FS, name)
+}
+
+// ReadDir merges the directory entries of all the layers, sorted by name.
+func (o *Overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	merged := map[string]fs.DirEntry{}
+	found := false
+	for _, l := range o.layers {
+		entries, err := fs.ReadDir(l.FS, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		found = true
+		for _, e := range entries {
+			merged[e.Name()] = e
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := make([]fs.DirEntry, 0, len(merged))
+	for _, e := range merged {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// layered tells if there are any layers on top of the hack scripts.
+func (o *Overlay) layered() bool {
+	return len(o.layers) > 1
+}
@@ -0,0 +1,35 @@
+package extract
+
+// FileStatus is the outcome of extracting a single file.
+type FileStatus string
+
+const (
+	// StatusWritten is the status of a file written to the disk.
+	StatusWritten FileStatus = "written"
+	// StatusUpToDate is the status of a file that was already extracted.
+	StatusUpToDate FileStatus = "up-to-date"
+	// StatusPruned is the status of a file removed from the disk, as it was
+	// extracted before, but isn't in the library anymore.
+	StatusPruned FileStatus = "pruned"
+	// StatusStale is the status of a file that would be pruned, if not for
+	// the dry-run mode.
+	StatusStale FileStatus = "stale"
+)
+
+// FileRecord describes the extraction of a single file.
+type FileRecord struct {
+	Path   string     `json:"path"`
+	Status FileStatus `json:"status"`
+	Bytes  int64      `json:"bytes"`
+	Digest string     `json:"digest"`
+	Layer  string     `json:"layer"`
+}
+
+// Extraction describes the outcome of the Operation.
+type Extraction struct {
+	// Root is the directory the scripts were extracted to.
+	Root string `json:"root"`
+	// Script is the path of the requested script.
+	Script string       `json:"script"`
+	Files  []FileRecord `json:"files"`
+}
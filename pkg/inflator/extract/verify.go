@@ -0,0 +1,108 @@
+package extract
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ErrDrift is returned when the extracted scripts differ from the embedded
+// ones.
+var ErrDrift = errors.New("scripts differ from the embedded copies")
+
+// Entry describes a file embedded in the library.
+type Entry struct {
+	Name   string
+	Size   int64
+	Digest string
+	// Layer is the name of the layer the file comes from.
+	Layer string
+}
+
+// List returns the files of the library, with the layers stacked on top of
+// it, sorted by name.
+func List(layers ...Layer) ([]Entry, error) {
+	var entries []Entry
+	scripts := Stack(layers...)
+	err := fs.WalkDir(scripts, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		bytes, err := scripts.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		layer, err := scripts.Layer(filePath)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, Entry{
+			Name:   filePath,
+			Size:   int64(len(bytes)),
+			Digest: digest(bytes),
+			Layer:  layer,
+		})
+		return nil
+	})
+	return entries, wrapErr(err, ErrBug)
+}
+
+// DriftKind is the kind of difference between a directory and the library.
+type DriftKind string
+
+const (
+	// DriftMissing is a file of the library missing in the directory.
+	DriftMissing DriftKind = "missing"
+	// DriftModified is a file with a content other than in the library.
+	DriftModified DriftKind = "modified"
+	// DriftExtra is a script in the directory, that isn't in the library.
+	DriftExtra DriftKind = "extra"
+)
+
+// Drift is a single difference between a directory and the library.
+type Drift struct {
+	Kind DriftKind
+	Name string
+}
+
+// Verify compares the files in the directory with the ones of the library,
+// with the layers stacked on top of it. The differences are returned sorted by
+// name.
+func Verify(dir string, layers ...Layer) ([]Drift, error) {
+	entries, err := List(layers...)
+	if err != nil {
+		return nil, err
+	}
+	var drifts []Drift
+	known := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		known[e.Name] = true
+		d, err := fileDigest(path.Join(dir, e.Name))
+		if err != nil {
+			return nil, wrapErr(err, ErrUnexpected)
+		}
+		switch d {
+		case e.Digest:
+		case "":
+			drifts = append(drifts, Drift{Kind: DriftMissing, Name: e.Name})
+		default:
+			drifts = append(drifts, Drift{Kind: DriftModified, Name: e.Name})
+		}
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, wrapErr(err, ErrUnexpected)
+	}
+	for _, f := range files {
+		if !f.IsDir() && !known[f.Name()] && strings.HasSuffix(f.Name(), ".sh") {
+			drifts = append(drifts, Drift{Kind: DriftExtra, Name: f.Name()})
+		}
+	}
+	sort.SliceStable(drifts, func(i, j int) bool {
+		return drifts[i].Name < drifts[j].Name
+	})
+	return drifts, nil
+}
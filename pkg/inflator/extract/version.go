@@ -0,0 +1,98 @@
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"runtime/debug"
+	"sync"
+
+	"knative.dev/hack"
+)
+
+const (
+	hackModulePath = "knative.dev/hack"
+	develVersion   = "(devel)"
+	// digestVersionPrefix marks the versions derived from the content digest
+	// of the scripts, used when the module version isn't known.
+	digestVersionPrefix = "sha256-"
+	digestVersionLength = 12
+)
+
+var (
+	unsafeVersionChars = regexp.MustCompile(`[^a-zA-Z0-9._+-]+`)
+
+	versionOnce sync.Once //nolint:gochecknoglobals
+	version     string    //nolint:gochecknoglobals
+)
+
+// BaseDir returns the directory holding the versioned extraction directories.
+func BaseDir() string {
+	return path.Join(os.TempDir(), "knative", "hack", "scripts")
+}
+
+// DefaultDir returns the directory the scripts are extracted to, unless
+// overridden with the HackScriptsDirEnvVar environment variable. It's keyed
+// by the Version, so different versions of the scripts don't overwrite each
+// other.
+func DefaultDir() string {
+	return path.Join(BaseDir(), Version())
+}
+
+// Version returns the version of the knative.dev/hack module, as recorded in
+// the build info. If it isn't known, as for local builds or replaced modules,
+// the content digest of the scripts is returned instead.
+func Version() string {
+	versionOnce.Do(func() {
+		version = moduleVersion()
+		if version == "" {
+			version = contentVersion(hack.Scripts)
+		}
+	})
+	return version
+}
+
+func moduleVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	mods := append([]*debug.Module{&bi.Main}, bi.Deps...)
+	for _, mod := range mods {
+		if mod.Path != hackModulePath {
+			continue
+		}
+		if mod.Replace != nil {
+			mod = mod.Replace
+		}
+		if mod.Version == "" || mod.Version == develVersion {
+			return ""
+		}
+		return unsafeVersionChars.ReplaceAllString(mod.Version, "_")
+	}
+	return ""
+}
+
+// contentVersion digests the names and contents of all files in the scripts.
+func contentVersion(fsys fs.FS) string {
+	h := sha256.New()
+	err := fs.WalkDir(fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		bytes, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+		_, _ = h.Write([]byte(filePath + "\x00"))
+		_, _ = h.Write(bytes)
+		return nil
+	})
+	if err != nil {
+		return "unknown"
+	}
+	return digestVersionPrefix + hex.EncodeToString(h.Sum(nil))[:digestVersionLength]
+}
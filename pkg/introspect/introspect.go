@@ -0,0 +1,244 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package introspect parses the hack scripts, and lists the functions they
+// declare, together with their documentation.
+package introspect
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"knative.dev/hack"
+	"knative.dev/hack/shell"
+)
+
+var (
+	// ErrUnknownScript is returned when the script isn't in the catalog.
+	ErrUnknownScript = errors.New("unknown script")
+	// ErrUnknownFunction is returned when the function isn't declared by
+	// the script, nor by the scripts it sources.
+	ErrUnknownFunction = errors.New("unknown function")
+)
+
+// Catalog holds the parsed scripts.
+type Catalog struct {
+	Scripts []Script
+}
+
+// Script is a parsed shell script.
+type Script struct {
+	// Name is the path of the script within the parsed file system.
+	Name string
+	// Sources lists the scripts sourced by this script.
+	Sources []string
+	// Functions lists the functions declared by the script, in order.
+	Functions []Function
+}
+
+// Function is a shell function declared in a script.
+type Function struct {
+	Name string
+	// Line is the 1-based line number of the function declaration.
+	Line int
+	// Doc is the description from the comment block above the function,
+	// without the parameters and the deprecation notice.
+	Doc        string
+	Parameters []Parameter
+	Deprecated bool
+	// Deprecation is the text following the "Deprecated:" marker.
+	Deprecation string
+}
+
+// Parameter is a documented parameter of a function, like "$1" or
+// "[--color <color>]".
+type Parameter struct {
+	Name        string
+	Description string
+}
+
+var (
+	functionRe  = regexp.MustCompile(`^\s*(?:function\s+([\w:.-]+)\s*(?:\(\s*\))?|([\w:.-]+)\s*\(\s*\))\s*\{?\s*$`)
+	sourceRe    = regexp.MustCompile(`^source\s+.*?([\w.-]+\.sh)"?\s*$`)
+	parameterRe = regexp.MustCompile(`^(\[[^\]]*\]|\$\S*(?:\s+-\s+\$\w+)?)\s+-\s+(.*)$`)
+)
+
+const (
+	parametersMarker = "Parameters:"
+	deprecatedMarker = "Deprecated:"
+)
+
+// Default parses the scripts embedded in hack.Scripts.
+func Default() (*Catalog, error) {
+	return Load(hack.Scripts)
+}
+
+// Load parses all the "*.sh" files in the root of the file system.
+func Load(fsys fs.FS) (*Catalog, error) {
+	names, err := fs.Glob(fsys, "*.sh")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	c := &Catalog{Scripts: make([]Script, 0, len(names))}
+	for _, name := range names {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		sc, err := Parse(name, f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		c.Scripts = append(c.Scripts, sc)
+	}
+	return c, nil
+}
+
+// Parse reads a script, and collects its documented functions.
+func Parse(name string, r io.Reader) (Script, error) {
+	sc := Script{Name: name}
+	var comments []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "#") {
+			comments = append(comments, strings.TrimPrefix(trimmed, "#"))
+			continue
+		}
+		if m := sourceRe.FindStringSubmatch(trimmed); m != nil {
+			sc.Sources = append(sc.Sources, m[1])
+		}
+		if m := functionRe.FindStringSubmatch(text); m != nil {
+			fn := Function{Name: m[1] + m[2], Line: line}
+			fn.document(comments)
+			sc.Functions = append(sc.Functions, fn)
+		}
+		comments = nil
+	}
+	return sc, scanner.Err()
+}
+
+// document fills the function documentation from the comment block above it.
+func (fn *Function) document(comments []string) {
+	var doc []string
+	inParams := false
+	for _, c := range comments {
+		trimmed := strings.TrimSpace(c)
+		switch {
+		case strings.HasPrefix(trimmed, parametersMarker):
+			inParams = true
+			fn.addParameter(strings.TrimSpace(strings.TrimPrefix(trimmed, parametersMarker)))
+			continue
+		case strings.HasPrefix(trimmed, deprecatedMarker):
+			inParams = false
+			fn.Deprecated = true
+			fn.Deprecation = strings.TrimSpace(strings.TrimPrefix(trimmed, deprecatedMarker))
+			continue
+		case inParams && parameterRe.MatchString(trimmed):
+			fn.addParameter(trimmed)
+			continue
+		case inParams && trimmed != "" && strings.HasPrefix(c, "  ") && len(fn.Parameters) > 0:
+			last := &fn.Parameters[len(fn.Parameters)-1]
+			last.Description = strings.TrimSpace(last.Description + " " + trimmed)
+			continue
+		}
+		inParams = false
+		doc = append(doc, trimmed)
+	}
+	fn.Doc = strings.TrimSpace(strings.Join(doc, "\n"))
+}
+
+func (fn *Function) addParameter(text string) {
+	if text == "" {
+		return
+	}
+	if m := parameterRe.FindStringSubmatch(text); m != nil {
+		fn.Parameters = append(fn.Parameters, Parameter{Name: m[1], Description: m[2]})
+		return
+	}
+	fn.Parameters = append(fn.Parameters, Parameter{Description: text})
+}
+
+// Script returns the script of a given name. The directory of the name is
+// ignored, so vendored paths like "vendor/knative.dev/hack/library.sh" match
+// as well.
+func (c *Catalog) Script(name string) (Script, bool) {
+	base := path.Base(name)
+	for _, sc := range c.Scripts {
+		if sc.Name == base {
+			return sc, true
+		}
+	}
+	return Script{}, false
+}
+
+// Function returns the function declared directly in the script.
+func (sc Script) Function(name string) (Function, bool) {
+	for _, fn := range sc.Functions {
+		if fn.Name == name {
+			return fn, true
+		}
+	}
+	return Function{}, false
+}
+
+// Lookup finds the function available after sourcing the given script,
+// following the scripts it sources. The script declaring the function is
+// returned as well.
+func (c *Catalog) Lookup(script, function string) (Script, Function, error) {
+	sc, ok := c.Script(script)
+	if !ok {
+		return Script{}, Function{}, fmt.Errorf("%w: %s", ErrUnknownScript, script)
+	}
+	seen := map[string]bool{}
+	queue := []Script{sc}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if seen[cur.Name] {
+			continue
+		}
+		seen[cur.Name] = true
+		if fn, ok := cur.Function(function); ok {
+			return cur, fn, nil
+		}
+		for _, src := range cur.Sources {
+			if dep, ok := c.Script(src); ok {
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return Script{}, Function{}, fmt.Errorf("%w: %s in %s",
+		ErrUnknownFunction, function, sc.Name)
+}
+
+// Check verifies that the shell function is available in its script, before
+// running it.
+func (c *Catalog) Check(fn shell.Function) error {
+	_, _, err := c.Lookup(fn.ScriptPath, fn.FunctionName)
+	return err
+}
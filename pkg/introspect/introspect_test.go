@@ -0,0 +1,101 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package introspect_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"knative.dev/hack/pkg/introspect"
+	"knative.dev/hack/pkg/utest/assert"
+	"knative.dev/hack/pkg/utest/require"
+	"knative.dev/hack/shell"
+)
+
+const example = `#!/usr/bin/env bash
+
+source "$(dirname "${BASH_SOURCE[0]}")/library.sh"
+
+# Greets somebody.
+# Parameters: $1 - name of the person,
+#                  with a continuation.
+#             $2..$n - ignored.
+# Deprecated: use hello instead
+function greet() {
+  echo "Hello, $1"
+}
+
+undocumented() {
+  :
+}
+`
+
+func TestParse(t *testing.T) {
+	sc, err := introspect.Parse("example.sh", strings.NewReader(example))
+	require.NoError(t, err)
+	assert.Equal(t, "library.sh", strings.Join(sc.Sources, ","))
+	if len(sc.Functions) != 2 {
+		t.Fatalf("want 2 functions, got %+v", sc.Functions)
+	}
+	greet := sc.Functions[0]
+	assert.Equal(t, "greet", greet.Name)
+	assert.Equal(t, 10, greet.Line)
+	assert.Equal(t, "Greets somebody.", greet.Doc)
+	assert.Equal(t, 2, len(greet.Parameters))
+	assert.Equal(t, introspect.Parameter{
+		Name: "$1", Description: "name of the person, with a continuation.",
+	}, greet.Parameters[0])
+	assert.Equal(t, introspect.Parameter{
+		Name: "$2..$n", Description: "ignored.",
+	}, greet.Parameters[1])
+	assert.Equal(t, true, greet.Deprecated)
+	assert.Equal(t, "use hello instead", greet.Deprecation)
+	undocumented := sc.Functions[1]
+	assert.Equal(t, "undocumented", undocumented.Name)
+	assert.Equal(t, 14, undocumented.Line)
+	assert.Equal(t, "", undocumented.Doc)
+}
+
+func TestDefault(t *testing.T) {
+	c, err := introspect.Default()
+	require.NoError(t, err)
+
+	sc, fn, err := c.Lookup("e2e-tests.sh", "run_go_tool")
+	require.NoError(t, err)
+	assert.Equal(t, "library.sh", sc.Name)
+	assert.Equal(t, true, fn.Deprecated)
+	assert.Equal(t, 3, len(fn.Parameters))
+
+	_, fn, err = c.Lookup("library.sh", "gum_banner")
+	require.NoError(t, err)
+	assert.Equal(t, "[--border <type>]", fn.Parameters[0].Name)
+
+	_, _, err = c.Lookup("library.sh", "go_test_e2e")
+	assert.Equal(t, true, errors.Is(err, introspect.ErrUnknownFunction))
+
+	err = c.Check(shell.Function{
+		Script:       shell.Script{ScriptPath: "vendor/knative.dev/hack/release.sh"},
+		FunctionName: "abort",
+	})
+	require.NoError(t, err)
+	err = c.Check(shell.Function{
+		Script:       shell.Script{ScriptPath: "hack/update-codegen.sh"},
+		FunctionName: "abort",
+	})
+	assert.Equal(t, true, errors.Is(err, introspect.ErrUnknownScript))
+}
@@ -0,0 +1,113 @@
+package retcode
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Code is a well-known retcode, bound to a sentinel error.
+type Code struct {
+	Code int
+	Err  error
+	// Name is the qualified name of the sentinel error, like
+	// "knative.dev/hack/shell.ErrNoProjectLocation".
+	Name string
+	// Description explains the failure, and how to deal with it.
+	Description string
+}
+
+var (
+	registryMu sync.RWMutex     //nolint:gochecknoglobals
+	registry   = map[int]Code{} //nolint:gochecknoglobals
+)
+
+// Register binds the sentinel error to the retcode, so it's returned by Calc
+// for any error wrapping the sentinel. It's meant to be called from the init
+// functions of the packages declaring the errors, and it panics if the code is
+// already bound to another error.
+//
+// The codes of sysexits.h registered in this repository, 64, 65, 70, 74 and
+// 78, fall inside the 1-254 range of the codes calculated from the messages.
+// A calculated code can collide with them, so a well-known code alone
+// doesn't tell which error caused it.
+func Register(code Code) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if prev, ok := registry[code.Code]; ok && prev.Err != code.Err {
+		panic(fmt.Sprintf("retcode %d is already registered for %s",
+			code.Code, prev.Name))
+	}
+	registry[code.Code] = code
+}
+
+// Lookup returns the well-known retcode registered with Register.
+func Lookup(code int) (Code, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	c, ok := registry[code]
+	return c, ok
+}
+
+// Codes returns all the well-known retcodes, sorted by the code.
+func Codes() []Code {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	codes := make([]Code, 0, len(registry))
+	for _, c := range registry {
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		return codes[i].Code < codes[j].Code
+	})
+	return codes
+}
+
+// lookupErr finds the first registered sentinel in the error tree, walking it
+// depth-first, as errors.Is does.
+func lookupErr(err error) (Code, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if len(registry) == 0 {
+		return Code{}, false
+	}
+	var (
+		found Code
+		ok    bool
+	)
+	walk(err, func(e error) bool {
+		if !reflect.TypeOf(e).Comparable() {
+			return false
+		}
+		for _, c := range registry {
+			if c.Err == e {
+				found, ok = c, true
+				return true
+			}
+		}
+		return false
+	})
+	return found, ok
+}
+
+// walk visits the errors of the tree, until the visitor returns true.
+func walk(err error, visit func(error) bool) bool {
+	if err == nil {
+		return false
+	}
+	if visit(err) {
+		return true
+	}
+	switch x := err.(type) {
+	case interface{ Unwrap() error }:
+		return walk(x.Unwrap(), visit)
+	case interface{ Unwrap() []error }:
+		for _, e := range x.Unwrap() {
+			if walk(e, visit) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package retcode_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"knative.dev/hack/pkg/retcode"
+	"knative.dev/hack/pkg/utest/assert"
+)
+
+var errSentinel = errors.New("sentinel")
+
+func TestCalcWalksErrorChain(t *testing.T) {
+	retcode.Register(retcode.Code{Code: 99, Err: errSentinel, Name: "errSentinel"})
+
+	wrapped := fmt.Errorf("%w: %w", errSentinel, retcodeErr{})
+	assert.Equal(t, 42, retcode.Calc(wrapped))
+	assert.Equal(t, 42, retcode.Calc(fmt.Errorf("outer: %w", wrapped)))
+	assert.Equal(t, 99, retcode.Calc(fmt.Errorf("outer: %w", errSentinel)))
+	assert.Equal(t, 99, retcode.Calc(errors.Join(errors.New("other"), errSentinel)))
+	assert.Equal(t, 42, retcode.Calc(errors.Join(errSentinel, retcodeErr{})))
+
+	c, ok := retcode.Lookup(99)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "errSentinel", c.Name)
+	_, ok = retcode.Lookup(98)
+	assert.Equal(t, false, ok)
+}
+
+func TestRegisterConflict(t *testing.T) {
+	retcode.Register(retcode.Code{Code: 97, Err: errSentinel, Name: "errSentinel"})
+	defer func() {
+		assert.Equal(t, true, recover() != nil)
+	}()
+	retcode.Register(retcode.Code{Code: 97, Err: errors.New("other"), Name: "other"})
+}
@@ -1,6 +1,9 @@
 package retcode
 
-import "hash/crc32"
+import (
+	"errors"
+	"hash/crc32"
+)
 
 var (
 	// LowerBound is the lower bound of the POSIX retcode range. Use this to
@@ -9,18 +12,84 @@ var (
 	// UpperBound is the upper bound of the POSIX retcode range. Use this to
 	// configure the package.
 	UpperBound = 255
+
+	// Default is the algorithm used by Calc. Set it to HashCode, to get the
+	// same retcodes as the abort function of library.sh.
+	Default Algorithm = CRC32
 )
 
+// Algorithm calculates a POSIX retcode from an error message.
+type Algorithm func(message string) int
+
 // Calc will calculate an POSIX retcode from an error.
 func Calc(err error) int {
+	return CalcWith(err, Default)
+}
+
+// CalcWith will calculate an POSIX retcode from an error, with the given
+// algorithm. The error chain, including the joined errors, is searched for
+// an error with a Retcode() int method first, and then for a sentinel error
+// registered with Register. Only if neither is found, the algorithm is used.
+// An error never gives 0: if the algorithm returns it, like HashCode does for
+// some messages, the LowerBound is returned instead.
+func CalcWith(err error, algorithm Algorithm) int {
 	if err == nil {
 		return 0
 	}
-	if r, ok := err.(retcodeErr); ok {
+	var r retcodeErr
+	if errors.As(err, &r) {
 		return r.Retcode()
 	}
+	if c, ok := lookupErr(err); ok {
+		return c.Code
+	}
+	if rc := algorithm(err.Error()); rc != 0 {
+		return rc
+	}
+	return LowerBound
+}
+
+// CRC32 calculates the retcode from the CRC32 checksum of the message, within
+// the LowerBound and UpperBound.
+func CRC32(message string) int {
 	upper := UpperBound - LowerBound
-	return int(crc32.ChecksumIEEE([]byte(err.Error())))%upper + LowerBound
+	return int(crc32.ChecksumIEEE([]byte(message)))%upper + LowerBound
+}
+
+// HashCode calculates the retcode the same way as the calcRetcode function of
+// library.sh, from the Java-like hashCode of the message. The characters are
+// taken as Unicode code points, as bash does in UTF-8 locales. The bounds
+// aren't used, as the retcodes are always within 0 and 254. As in library.sh,
+// the hashes that are a multiple of 255 give 0, which CalcWith replaces.
+func HashCode(message string) int {
+	rc := 1
+	if h := hashCode(message); h != 0 {
+		rc = int(h % 255)
+	}
+	return rc
+}
+
+// hashCode mirrors the hashCode function of library.sh, including the way it
+// folds the values overflowing the 32-bit integer range.
+func hashCode(input string) int64 {
+	const (
+		maxInt32 = 2147483647
+		minInt32 = -2147483648
+		modulo   = 2147483648
+	)
+	var h int64
+	for _, r := range input {
+		hval := 31*h + int64(r)
+		switch {
+		case hval > maxInt32:
+			h = (hval - modulo) % modulo
+		case hval < minInt32:
+			h = (hval + modulo) % modulo
+		default:
+			h = hval
+		}
+	}
+	return h
 }
 
 type retcodeErr interface {
@@ -0,0 +1,103 @@
+package retcode_test
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"knative.dev/hack"
+	"knative.dev/hack/pkg/retcode"
+	"knative.dev/hack/pkg/utest/assert"
+	"knative.dev/hack/pkg/utest/require"
+)
+
+type golden struct {
+	retcode int
+	message string
+}
+
+func TestHashCodeGolden(t *testing.T) {
+	for _, g := range readGolden(t) {
+		assert.Equal(t, g.retcode, retcode.HashCode(g.message), g.message)
+	}
+}
+
+func TestHashCodeMatchesLibrary(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	lib, err := hack.Scripts.ReadFile("library.sh")
+	require.NoError(t, err)
+	// only the functions are taken, as sourcing the whole library.sh runs
+	// go tools, and requires network access
+	fns := regexp.MustCompile(`(?ms)^function (?:hashCode|calcRetcode)\(\) \{$.*?^}$`).
+		FindAllString(string(lib), -1)
+	assert.Equal(t, 2, len(fns))
+	script := strings.Join(fns, "\n") + `
+while IFS= read -r msg; do
+  calcRetcode "$msg"
+done < testdata/messages.txt
+`
+	c := exec.Command("bash", "-c", script)
+	c.Env = append(os.Environ(), "LC_ALL=C.UTF-8")
+	out, err := c.Output()
+	require.NoError(t, err)
+	codes := strings.Fields(string(out))
+	gs := readGolden(t)
+	assert.Equal(t, len(gs), len(codes))
+	for i, g := range gs {
+		if i < len(codes) {
+			assert.Equal(t, strconv.Itoa(g.retcode), codes[i], g.message)
+		}
+	}
+}
+
+func TestCalcWith(t *testing.T) {
+	err := errors.New("boom")
+	assert.Equal(t, 0, retcode.CalcWith(nil, retcode.HashCode))
+	assert.Equal(t, 84, retcode.CalcWith(err, retcode.HashCode))
+	assert.Equal(t, retcode.CRC32("boom"), retcode.CalcWith(err, retcode.CRC32))
+	assert.Equal(t, retcode.CRC32("boom"), retcode.Calc(err))
+	assert.Equal(t, 42, retcode.CalcWith(retcodeErr{}, retcode.HashCode))
+	assert.Equal(t, 0, retcode.HashCode("error 317"))
+	assert.Equal(t, 1, retcode.CalcWith(errors.New("error 317"), retcode.HashCode))
+
+	prev := retcode.Default
+	defer func() {
+		retcode.Default = prev
+	}()
+	retcode.Default = retcode.HashCode
+	assert.Equal(t, 84, retcode.Calc(err))
+}
+
+type retcodeErr struct{}
+
+func (retcodeErr) Error() string {
+	return "custom"
+}
+
+func (retcodeErr) Retcode() int {
+	return 42
+}
+
+func readGolden(t *testing.T) []golden {
+	t.Helper()
+	f, err := os.Open("testdata/retcodes.golden")
+	require.NoError(t, err)
+	defer f.Close()
+	var gs []golden
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		code, msg, _ := strings.Cut(sc.Text(), "\t")
+		rc, err := strconv.Atoi(code)
+		require.NoError(t, err)
+		gs = append(gs, golden{retcode: rc, message: msg})
+	}
+	require.NoError(t, sc.Err())
+	return gs
+}
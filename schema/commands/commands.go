@@ -17,18 +17,22 @@ limitations under the License.
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"strings"
+	"reflect"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
 
 	"knative.dev/hack/schema/docs"
 	"knative.dev/hack/schema/registry"
 	"knative.dev/hack/schema/schema"
 )
 
+var errOutOfDate = errors.New("the manifest is out of date")
+
 // New creates a new schema cli command set.
 func New(root string) *cobra.Command {
 	docs.SetRoot(root)
@@ -39,29 +43,54 @@ func New(root string) *cobra.Command {
 	}
 
 	addDumpCmd(cmd)
+	addCRDCmd(cmd)
+	addUpdateCmd(cmd)
 
 	return cmd
 }
 
 func addDumpCmd(root *cobra.Command) {
-	var kind string
+	var (
+		query k8sschema.GroupVersionKind
+		gvk   k8sschema.GroupVersionKind
+	)
 
 	var cmd = &cobra.Command{
-		Use:   "dump <kind>",
-		Short: "Dump the raw output of schemas of known kinds.",
-		Args:  cobra.ExactArgs(1),
+		Use:   "dump [<kind>]",
+		Short: "Dump the raw output of schemas of known kinds, or list the known kinds.",
+		Long: "Dump the raw output of the schema of the kind. The kind is given as Kind, " +
+			"Kind.group or group/version/Kind, and has to match exactly one registered " +
+			"type. Without the kind, the registered kinds are listed.",
+		Args: cobra.MaximumNArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			// Validation
-			kind = args[0]
-			if t := registry.TypeFor(kind); t == nil {
-				known := registry.Kinds()
-				return fmt.Errorf("unknown Kind: %s, expected one of [%s]", kind, strings.Join(known, ", "))
+			if len(args) == 0 {
+				return nil
 			}
-			return nil
+			ref, err := registry.ParseRef(args[0])
+			if err != nil {
+				return err
+			}
+			if err = mergeQuery(&query, ref); err != nil {
+				return err
+			}
+			if ref.Version != "" {
+				// the full reference is exact, also for the core group
+				gvk, err = registry.Lookup(args[0])
+				return err
+			}
+			gvk, err = registry.Find(query)
+			return err
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			s := schema.GenerateForType(registry.TypeFor(kind))
-			enc := yaml.NewEncoder(os.Stdout)
+			if len(args) == 0 {
+				for _, gvk := range registry.Select(query) {
+					fmt.Fprintln(cmd.OutOrStdout(), registry.FormatRef(gvk))
+				}
+				return nil
+			}
+			s := schema.GenerateForType(registry.TypeForGVK(gvk))
+			enc := yaml.NewEncoder(cmd.OutOrStdout())
 			enc.SetIndent(2)
 			err := enc.Encode(s)
 			if err != nil {
@@ -70,7 +99,140 @@ func addDumpCmd(root *cobra.Command) {
 			return nil
 		},
 	}
-	// TODO: add support for versions, etc.
+	cmd.Flags().StringVar(&query.Group, "group", "", "the API group of the kind")
+	cmd.Flags().StringVar(&query.Version, "version", "", "the API version of the kind")
+
+	root.AddCommand(cmd)
+}
+
+func addCRDCmd(root *cobra.Command) {
+	var (
+		query k8sschema.GroupVersionKind
+		gvks  []k8sschema.GroupVersionKind
+	)
+
+	var cmd = &cobra.Command{
+		Use:   "crd <kind>",
+		Short: "Generate the CustomResourceDefinition of a known kind.",
+		Long: "Generate the apiextensions.k8s.io/v1 CustomResourceDefinition serving all of " +
+			"the registered versions of the kind. The kind is given as Kind or Kind.group. " +
+			"The scope, names, printer columns and subresources are taken from the " +
+			"kubebuilder markers of the types, and the options of the registry.",
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Validation
+			ref, err := registry.ParseRef(args[0])
+			if err != nil {
+				return err
+			}
+			if ref.Version != "" {
+				return fmt.Errorf("the CRD covers all versions, expected Kind or Kind.group, got %q", args[0])
+			}
+			if err = mergeQuery(&query, ref); err != nil {
+				return err
+			}
+			gvks, err = registry.Versions(query)
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			versions := make([]schema.Version, 0, len(gvks))
+			for _, gvk := range gvks {
+				res, err := registry.Resource(gvk)
+				if err != nil {
+					return err
+				}
+				versions = append(versions, schema.Version{
+					Name:     gvk.Version,
+					Type:     registry.TypeForGVK(gvk),
+					Resource: res,
+				})
+			}
+			crd, err := schema.GenerateCRD(gvks[0].Group, gvks[0].Kind, versions...)
+			if err != nil {
+				return err
+			}
+			enc := yaml.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent(2)
+			return enc.Encode(crd)
+		},
+	}
+	cmd.Flags().StringVar(&query.Group, "group", "", "the API group of the kind")
+
+	root.AddCommand(cmd)
+}
+
+func addUpdateCmd(root *cobra.Command) {
+	var (
+		file  string
+		check bool
+	)
+
+	var cmd = &cobra.Command{
+		Use:   "update --file <manifest>",
+		Short: "Update the schemas of the CRDs in a manifest, in place.",
+		Long: "Update the schema.openAPIV3Schema of the versions of the " +
+			"CustomResourceDefinitions in the manifest, which kinds are registered. " +
+			"Only the lines of the schemas are replaced, the rest of the manifest is kept as is. " +
+			"With --check, the manifest isn't written, and the command fails if it's out of date.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fi, err := os.Stat(file)
+			if err != nil {
+				return err
+			}
+			manifest, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			updated, changed, err := schema.UpdateManifest(manifest, typeForGVK)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			switch {
+			case !changed:
+				fmt.Fprintf(cmd.OutOrStdout(), "%s is up to date\n", file)
+			case check:
+				return fmt.Errorf("%w: %s, run: schema update --file %s", errOutOfDate, file, file)
+			default:
+				if err = os.WriteFile(file, updated, fi.Mode().Perm()); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s updated\n", file)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "the CRD manifest to update")
+	cmd.Flags().BoolVar(&check, "check", false, "fail if the manifest is out of date, instead of updating it")
+	_ = cmd.MarkFlagRequired("file")
 
 	root.AddCommand(cmd)
 }
+
+func typeForGVK(group, version, kind string) reflect.Type {
+	return registry.TypeForGVK(k8sschema.GroupVersionKind{Group: group, Version: version, Kind: kind})
+}
+
+// mergeQuery fills the query with the reference, which can't contradict the
+// group and version given by the flags. The group of a full reference is set,
+// even if it's the empty core group.
+func mergeQuery(query *k8sschema.GroupVersionKind, ref k8sschema.GroupVersionKind) error {
+	full := ref.Version != ""
+	if (full || ref.Group != "") && query.Group != "" && ref.Group != query.Group {
+		return fmt.Errorf("the group of the kind %q conflicts with --group=%s",
+			registry.FormatQuery(ref), query.Group)
+	}
+	if ref.Version != "" && query.Version != "" && ref.Version != query.Version {
+		return fmt.Errorf("the version of the kind %q conflicts with --version=%s",
+			registry.FormatQuery(ref), query.Version)
+	}
+	query.Kind = ref.Kind
+	if ref.Group != "" {
+		query.Group = ref.Group
+	}
+	if ref.Version != "" {
+		query.Version = ref.Version
+	}
+	return nil
+}
@@ -78,6 +78,35 @@ func GetDocsForField(t reflect.Type, fieldName string) (string, OpenAPIRequired,
 	return "", Unknown, fmt.Errorf("did not find doc for %q", t.Name())
 }
 
+// GetMarkersForType returns the markers, the lines starting with a plus sign,
+// of the doc comment of the type. The plus sign is trimmed.
+func GetMarkersForType(t reflect.Type) ([]string, error) {
+	pkg := t.PkgPath()
+	pm, err := makeParserMapForPackage(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse dir: %w", err)
+	}
+	p, present := pm[pkg]
+	if !present {
+		return nil, fmt.Errorf("package not present: %q", pkg)
+	}
+	dp := doc.New(p, pkg, doc.PreserveAST)
+	for _, dt := range dp.Types {
+		if dt.Name != t.Name() || dt.Decl.Doc == nil {
+			continue
+		}
+		var markers []string
+		for _, line := range dt.Decl.Doc.List {
+			l := strings.TrimSpace(strings.TrimPrefix(line.Text, "//"))
+			if strings.HasPrefix(l, "+") {
+				markers = append(markers, strings.TrimPrefix(l, "+"))
+			}
+		}
+		return markers, nil
+	}
+	return nil, nil
+}
+
 func ignoreDirectories(fi os.FileInfo) bool {
 	return !fi.IsDir()
 }
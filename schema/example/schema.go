@@ -21,6 +21,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// LoremIpsum is an example kind, served as a custom resource.
+// +kubebuilder:resource:shortName=li,categories=all;knative
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Donec",type=boolean,JSONPath=".status.donec"
+// +kubebuilder:printcolumn:name="Luctus",type=integer,JSONPath=".status.luctus",priority=1
 type LoremIpsum struct {
 	metav1.TypeMeta `json:",inline"`
 	// +optional
@@ -17,20 +17,117 @@ limitations under the License.
 package registry
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+
+	hackschema "knative.dev/hack/schema/schema"
+)
+
+var (
+	// ErrInvalidRef is returned for references that can't be parsed.
+	ErrInvalidRef = errors.New("invalid reference")
+	// ErrUnknownKind is returned when no registered type matches a reference.
+	ErrUnknownKind = errors.New("unknown Kind")
+	// ErrAmbiguousKind is returned when more than one registered type matches
+	// a reference.
+	ErrAmbiguousKind = errors.New("ambiguous Kind")
+	// ErrInvalidOption is returned for the options of a registered type, that
+	// would produce an invalid CustomResourceDefinition.
+	ErrInvalidOption = errors.New("invalid option")
 )
 
+// Registry holds the types, keyed by their GroupVersionKind.
 type Registry struct {
-	// easy for now
-	kinds map[string]reflect.Type
+	types   map[schema.GroupVersionKind]reflect.Type
+	options map[schema.GroupVersionKind][]Option
+}
+
+// New creates an empty Registry.
+func New() *Registry {
+	return &Registry{
+		types:   map[schema.GroupVersionKind]reflect.Type{},
+		options: map[schema.GroupVersionKind][]Option{},
+	}
+}
+
+// Option configures how the registered type is served as a custom resource.
+// The options take precedence over the markers of the type.
+type Option func(*hackschema.Resource)
+
+// WithScope sets the scope, hackschema.NamespaceScoped or
+// hackschema.ClusterScoped, of the resource. Any other scope fails the
+// Resource with ErrInvalidOption.
+func WithScope(scope string) Option {
+	return func(r *hackschema.Resource) {
+		r.Scope = scope
+	}
+}
+
+// WithNames sets the plural, singular and short names of the resource.
+func WithNames(plural, singular string, shortNames ...string) Option {
+	return func(r *hackschema.Resource) {
+		r.Names.Plural = plural
+		r.Names.Singular = singular
+		r.Names.ShortNames = shortNames
+	}
+}
+
+// WithCategories sets the categories, like "all", of the resource.
+func WithCategories(categories ...string) Option {
+	return func(r *hackschema.Resource) {
+		r.Names.Categories = categories
+	}
+}
+
+// WithStorageVersion marks the version as the one persisted in etcd.
+func WithStorageVersion() Option {
+	return func(r *hackschema.Resource) {
+		r.Storage = true
+	}
+}
+
+// WithPrinterColumns adds the columns printed by kubectl get.
+func WithPrinterColumns(columns ...hackschema.CustomResourceColumnDefinition) Option {
+	return func(r *hackschema.Resource) {
+		r.PrinterColumns = append(r.PrinterColumns, columns...)
+	}
+}
+
+// WithStatusSubresource enables the status subresource.
+func WithStatusSubresource() Option {
+	return func(r *hackschema.Resource) {
+		if r.Subresources == nil {
+			r.Subresources = &hackschema.CustomResourceSubresources{}
+		}
+		r.Subresources.Status = &hackschema.CustomResourceSubresourceStatus{}
+	}
 }
 
-var r = &Registry{
-	kinds: map[string]reflect.Type{},
+// WithScaleSubresource enables the scale subresource. The selectorPath may be
+// empty.
+func WithScaleSubresource(specPath, statusPath, selectorPath string) Option {
+	return func(r *hackschema.Resource) {
+		if r.Subresources == nil {
+			r.Subresources = &hackschema.CustomResourceSubresources{}
+		}
+		r.Subresources.Scale = &hackschema.CustomResourceSubresourceScale{
+			SpecReplicasPath:   specPath,
+			StatusReplicasPath: statusPath,
+		}
+		if selectorPath != "" {
+			r.Subresources.Scale.LabelSelectorPath = &selectorPath
+		}
+	}
 }
 
+var r = New()
+
 // GVKable indicates that a particular type can return metadata about the Kind.
 type GVKable interface {
 	// GetGroupVersionKind returns a GroupVersionKind. The name is chosen
@@ -39,20 +136,251 @@ type GVKable interface {
 	GetGroupVersionKind() schema.GroupVersionKind
 }
 
-func Register(obj GVKable) {
+// Register adds the type to the default registry.
+func Register(obj GVKable, opts ...Option) {
+	r.Register(obj, opts...)
+}
+
+// Kinds returns the sorted, distinct Kinds of the default registry.
+func Kinds() []string {
+	return r.Kinds()
+}
+
+// GVKs returns the sorted GroupVersionKinds of the default registry.
+func GVKs() []schema.GroupVersionKind {
+	return r.GVKs()
+}
+
+// Select returns the sorted GroupVersionKinds of the default registry that
+// match the query.
+func Select(query schema.GroupVersionKind) []schema.GroupVersionKind {
+	return r.Select(query)
+}
+
+// Find returns the only GroupVersionKind of the default registry that matches
+// the query.
+func Find(query schema.GroupVersionKind) (schema.GroupVersionKind, error) {
+	return r.Find(query)
+}
+
+// Lookup returns the only GroupVersionKind of the default registry that
+// matches the reference, see ParseRef.
+func Lookup(ref string) (schema.GroupVersionKind, error) {
+	return r.Lookup(ref)
+}
+
+// TypeFor returns the type registered in the default registry for the
+// reference, or nil if there isn't exactly one.
+func TypeFor(ref string) reflect.Type {
+	return r.TypeFor(ref)
+}
+
+// TypeForGVK returns the type registered in the default registry for the
+// GroupVersionKind, or nil.
+func TypeForGVK(gvk schema.GroupVersionKind) reflect.Type {
+	return r.TypeForGVK(gvk)
+}
+
+// Versions returns the versions of the only group and Kind of the default
+// registry that match the query.
+func Versions(query schema.GroupVersionKind) ([]schema.GroupVersionKind, error) {
+	return r.Versions(query)
+}
+
+// Resource returns how the type registered in the default registry is served
+// as a custom resource.
+func Resource(gvk schema.GroupVersionKind) (hackschema.Resource, error) {
+	return r.Resource(gvk)
+}
+
+// Register adds the type, replacing the one registered before for the same
+// GroupVersionKind.
+func (r *Registry) Register(obj GVKable, opts ...Option) {
 	t := reflect.TypeOf(obj)
 	gvk := obj.GetGroupVersionKind()
-	r.kinds[gvk.Kind] = t.Elem()
+	r.types[gvk] = t.Elem()
+	r.options[gvk] = opts
 }
 
-func Kinds() []string {
-	kinds := make([]string, 0)
-	for k := range r.kinds {
-		kinds = append(kinds, k)
+// Kinds returns the sorted, distinct Kinds.
+func (r *Registry) Kinds() []string {
+	seen := make(map[string]bool, len(r.types))
+	kinds := make([]string, 0, len(r.types))
+	for gvk := range r.types {
+		if !seen[gvk.Kind] {
+			seen[gvk.Kind] = true
+			kinds = append(kinds, gvk.Kind)
+		}
 	}
+	sort.Strings(kinds)
 	return kinds
 }
 
-func TypeFor(kind string) reflect.Type {
-	return r.kinds[kind]
+// GVKs returns the GroupVersionKinds sorted by the group and the Kind, and
+// then from the most to the least stable version.
+func (r *Registry) GVKs() []schema.GroupVersionKind {
+	return r.Select(schema.GroupVersionKind{})
+}
+
+// Select returns the sorted GroupVersionKinds that match the query. The
+// empty fields of the query match anything.
+func (r *Registry) Select(query schema.GroupVersionKind) []schema.GroupVersionKind {
+	gvks := make([]schema.GroupVersionKind, 0, len(r.types))
+	for gvk := range r.types {
+		if matches(query, gvk) {
+			gvks = append(gvks, gvk)
+		}
+	}
+	sort.Slice(gvks, func(i, j int) bool {
+		a, b := gvks[i], gvks[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return version.CompareKubeAwareVersionStrings(a.Version, b.Version) > 0
+	})
+	return gvks
+}
+
+// Find returns the only GroupVersionKind that matches the query.
+func (r *Registry) Find(query schema.GroupVersionKind) (schema.GroupVersionKind, error) {
+	gvks := r.Select(query)
+	switch len(gvks) {
+	case 1:
+		return gvks[0], nil
+	case 0:
+		return schema.GroupVersionKind{}, fmt.Errorf("%w: %s, expected one of [%s]",
+			ErrUnknownKind, FormatQuery(query), formatRefs(r.GVKs()))
+	default:
+		return schema.GroupVersionKind{}, fmt.Errorf("%w: %s, matches [%s]",
+			ErrAmbiguousKind, FormatQuery(query), formatRefs(gvks))
+	}
+}
+
+// Lookup returns the only GroupVersionKind that matches the reference, see
+// ParseRef. A full group/version/Kind reference matches exactly, so the empty
+// core group doesn't match the other groups.
+func (r *Registry) Lookup(ref string) (schema.GroupVersionKind, error) {
+	query, err := ParseRef(ref)
+	if err != nil {
+		return query, err
+	}
+	if strings.Contains(ref, "/") {
+		if _, ok := r.types[query]; !ok {
+			return schema.GroupVersionKind{}, fmt.Errorf("%w: %s, expected one of [%s]",
+				ErrUnknownKind, FormatRef(query), formatRefs(r.GVKs()))
+		}
+		return query, nil
+	}
+	return r.Find(query)
+}
+
+// TypeFor returns the type registered for the reference, or nil if there
+// isn't exactly one.
+func (r *Registry) TypeFor(ref string) reflect.Type {
+	gvk, err := r.Lookup(ref)
+	if err != nil {
+		return nil
+	}
+	return r.types[gvk]
+}
+
+// TypeForGVK returns the type registered for the GroupVersionKind, or nil.
+func (r *Registry) TypeForGVK(gvk schema.GroupVersionKind) reflect.Type {
+	return r.types[gvk]
+}
+
+// Versions returns the versions of the only group and Kind that match the
+// query, from the most to the least stable one.
+func (r *Registry) Versions(query schema.GroupVersionKind) ([]schema.GroupVersionKind, error) {
+	gvks := r.Select(query)
+	if len(gvks) == 0 {
+		return nil, fmt.Errorf("%w: %s, expected one of [%s]",
+			ErrUnknownKind, FormatQuery(query), formatRefs(r.GVKs()))
+	}
+	for _, gvk := range gvks[1:] {
+		if gvk.GroupKind() != gvks[0].GroupKind() {
+			return nil, fmt.Errorf("%w: %s, matches [%s]",
+				ErrAmbiguousKind, FormatQuery(query), formatRefs(gvks))
+		}
+	}
+	return gvks, nil
+}
+
+// Resource returns how the type registered for the GroupVersionKind is served
+// as a custom resource. The options of the registration are applied on top of
+// the markers of the type.
+func (r *Registry) Resource(gvk schema.GroupVersionKind) (hackschema.Resource, error) {
+	t, ok := r.types[gvk]
+	if !ok {
+		return hackschema.Resource{}, fmt.Errorf("%w: %s", ErrUnknownKind, FormatRef(gvk))
+	}
+	res, err := hackschema.ResourceForType(t)
+	if err != nil {
+		return res, err
+	}
+	for _, opt := range r.options[gvk] {
+		opt(&res)
+	}
+	if res.Scope != "" && res.Scope != hackschema.NamespaceScoped && res.Scope != hackschema.ClusterScoped {
+		return res, fmt.Errorf("%w: %s: scope %q, want %s or %s", ErrInvalidOption,
+			FormatRef(gvk), res.Scope, hackschema.NamespaceScoped, hackschema.ClusterScoped)
+	}
+	return res, nil
+}
+
+// ParseRef parses a reference to a registered type. The reference is either
+// a Kind, a Kind.group, or a full group/version/Kind, where the core group is
+// empty. The fields missing in the reference are left empty.
+func ParseRef(ref string) (schema.GroupVersionKind, error) {
+	var gvk schema.GroupVersionKind
+	switch parts := strings.Split(ref, "/"); len(parts) {
+	case 1:
+		gvk.Kind, gvk.Group, _ = strings.Cut(ref, ".")
+	case 3:
+		gvk = schema.GroupVersionKind{Group: parts[0], Version: parts[1], Kind: parts[2]}
+	}
+	if gvk.Kind == "" || strings.Contains(ref, "/") && gvk.Version == "" {
+		return schema.GroupVersionKind{}, fmt.Errorf(
+			"%w: %q, expected Kind, Kind.group or group/version/Kind", ErrInvalidRef, ref)
+	}
+	return gvk, nil
+}
+
+// FormatRef formats the GroupVersionKind as a group/version/Kind reference,
+// that ParseRef parses back. The core group is empty, as in /v1/Pod.
+func FormatRef(gvk schema.GroupVersionKind) string {
+	return strings.Join([]string{gvk.Group, gvk.Version, gvk.Kind}, "/")
+}
+
+// FormatQuery formats the query, like the one of Select. The empty fields,
+// which match anything, are shown as a star.
+func FormatQuery(query schema.GroupVersionKind) string {
+	if query.Version == "" && query.Group == "" {
+		return query.Kind
+	}
+	return strings.Join([]string{orStar(query.Group), orStar(query.Version), orStar(query.Kind)}, "/")
+}
+
+func formatRefs(gvks []schema.GroupVersionKind) string {
+	refs := make([]string, len(gvks))
+	for i, gvk := range gvks {
+		refs[i] = FormatRef(gvk)
+	}
+	return strings.Join(refs, ", ")
+}
+
+func orStar(s string) string {
+	if s == "" {
+		return "*"
+	}
+	return s
+}
+
+func matches(query, gvk schema.GroupVersionKind) bool {
+	return (query.Group == "" || query.Group == gvk.Group) &&
+		(query.Version == "" || query.Version == gvk.Version) &&
+		(query.Kind == "" || query.Kind == gvk.Kind)
 }
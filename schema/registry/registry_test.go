@@ -0,0 +1,208 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"knative.dev/hack/schema/registry"
+	hackschema "knative.dev/hack/schema/schema"
+)
+
+type fooV1alpha1 struct{}
+
+func (*fooV1alpha1) GetGroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "a.knative.dev", Version: "v1alpha1", Kind: "Foo"}
+}
+
+type fooV1 struct{}
+
+func (*fooV1) GetGroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "a.knative.dev", Version: "v1", Kind: "Foo"}
+}
+
+type fooV1beta1 struct{}
+
+func (*fooV1beta1) GetGroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "a.knative.dev", Version: "v1beta1", Kind: "Foo"}
+}
+
+type otherFoo struct{}
+
+func (*otherFoo) GetGroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "b.knative.dev", Version: "v1", Kind: "Foo"}
+}
+
+type bar struct{}
+
+func (*bar) GetGroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "b.knative.dev", Version: "v1", Kind: "Bar"}
+}
+
+type coreFoo struct{}
+
+func (*coreFoo) GetGroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Version: "v1", Kind: "Foo"}
+}
+
+func newRegistry() *registry.Registry {
+	r := registry.New()
+	r.Register(&fooV1alpha1{})
+	r.Register(&otherFoo{})
+	r.Register(&fooV1{})
+	r.Register(&bar{})
+	r.Register(&fooV1beta1{})
+	return r
+}
+
+func TestGVKsSorted(t *testing.T) {
+	r := newRegistry()
+	want := []string{
+		"a.knative.dev/v1/Foo",
+		"a.knative.dev/v1beta1/Foo",
+		"a.knative.dev/v1alpha1/Foo",
+		"b.knative.dev/v1/Bar",
+		"b.knative.dev/v1/Foo",
+	}
+	got := make([]string, 0, len(want))
+	for _, gvk := range r.GVKs() {
+		got = append(got, registry.FormatRef(gvk))
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("GVKs() = %v, want %v", got, want)
+	}
+	if kinds := r.Kinds(); !reflect.DeepEqual([]string{"Bar", "Foo"}, kinds) {
+		t.Errorf("Kinds() = %v", kinds)
+	}
+}
+
+func TestFormatRef(t *testing.T) {
+	core := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	ref := registry.FormatRef(core)
+	if ref != "/v1/Pod" {
+		t.Errorf("FormatRef() = %q, want /v1/Pod", ref)
+	}
+	if gvk, err := registry.ParseRef(ref); err != nil || gvk != core {
+		t.Errorf("ParseRef(%q) = %v, %v, want %v", ref, gvk, err, core)
+	}
+	for query, want := range map[schema.GroupVersionKind]string{
+		{Kind: "Foo"}:                "Foo",
+		{Version: "v1", Kind: "Foo"}: "*/v1/Foo",
+		{Group: "a.knative.dev"}:     "a.knative.dev/*/*",
+	} {
+		if got := registry.FormatQuery(query); got != want {
+			t.Errorf("FormatQuery(%v) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestLookupCoreGroup(t *testing.T) {
+	r := newRegistry()
+	// b.knative.dev/v1/Foo has the same version and Kind
+	ref := "/v1/Foo"
+	if _, err := r.Lookup(ref); !errors.Is(err, registry.ErrUnknownKind) {
+		t.Errorf("Lookup(%q) error = %v, want %v", ref, err, registry.ErrUnknownKind)
+	}
+	r.Register(&coreFoo{})
+	if got := r.TypeFor(ref); got != reflect.TypeOf(coreFoo{}) {
+		t.Errorf("TypeFor(%q) = %v, want %v", ref, got, reflect.TypeOf(coreFoo{}))
+	}
+	if got := r.TypeFor("b.knative.dev/v1/Foo"); got != reflect.TypeOf(otherFoo{}) {
+		t.Errorf("TypeFor(b.knative.dev/v1/Foo) = %v", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	r := newRegistry()
+	tests := []struct {
+		ref  string
+		want reflect.Type
+		err  error
+	}{
+		{ref: "Bar", want: reflect.TypeOf(bar{})},
+		{ref: "Foo.b.knative.dev", want: reflect.TypeOf(otherFoo{})},
+		{ref: "a.knative.dev/v1beta1/Foo", want: reflect.TypeOf(fooV1beta1{})},
+		{ref: "Foo", err: registry.ErrAmbiguousKind},
+		{ref: "Foo.a.knative.dev", err: registry.ErrAmbiguousKind},
+		{ref: "Baz", err: registry.ErrUnknownKind},
+		{ref: "a.knative.dev/v2/Foo", err: registry.ErrUnknownKind},
+		{ref: "v1/Foo", err: registry.ErrInvalidRef},
+		{ref: "a.knative.dev//Foo", err: registry.ErrInvalidRef},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			gvk, err := r.Lookup(tc.ref)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Lookup(%q) error = %v, want %v", tc.ref, err, tc.err)
+			}
+			if got := r.TypeForGVK(gvk); tc.err == nil && got != tc.want {
+				t.Errorf("Lookup(%q) = %v, want %v", tc.ref, got, tc.want)
+			}
+			if got := r.TypeFor(tc.ref); got != tc.want {
+				t.Errorf("TypeFor(%q) = %v, want %v", tc.ref, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	r := newRegistry()
+	gvk, err := r.Find(schema.GroupVersionKind{Version: "v1alpha1", Kind: "Foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.TypeForGVK(gvk); got != reflect.TypeOf(fooV1alpha1{}) {
+		t.Errorf("Find() = %v", got)
+	}
+	if got := len(r.Select(schema.GroupVersionKind{Group: "b.knative.dev"})); got != 2 {
+		t.Errorf("Select() matched %d kinds, want 2", got)
+	}
+}
+
+func TestVersionsAndResource(t *testing.T) {
+	r := newRegistry()
+	r.Register(&bar{}, registry.WithScope(hackschema.ClusterScoped),
+		registry.WithStatusSubresource())
+
+	gvks, err := r.Versions(schema.GroupVersionKind{Group: "a.knative.dev", Kind: "Foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gvks) != 3 || gvks[0].Version != "v1" {
+		t.Errorf("Versions() = %v", gvks)
+	}
+	if _, err = r.Versions(schema.GroupVersionKind{Kind: "Foo"}); !errors.Is(err, registry.ErrAmbiguousKind) {
+		t.Errorf("Versions(Foo) error = %v", err)
+	}
+
+	res, err := r.Resource((&bar{}).GetGroupVersionKind())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Scope != hackschema.ClusterScoped || res.Subresources == nil || res.Subresources.Status == nil {
+		t.Errorf("Resource() = %+v", res)
+	}
+
+	r.Register(&bar{}, registry.WithScope("cluster"))
+	if _, err = r.Resource((&bar{}).GetGroupVersionKind()); !errors.Is(err, registry.ErrInvalidOption) {
+		t.Errorf("Resource() with an invalid scope error = %v", err)
+	}
+}
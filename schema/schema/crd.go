@@ -0,0 +1,242 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	// NamespaceScoped is the scope of the resources living in a namespace.
+	NamespaceScoped = "Namespaced"
+	// ClusterScoped is the scope of the resources living outside namespaces.
+	ClusterScoped = "Cluster"
+)
+
+// ErrInvalidResource is returned when the versions of a kind can't be served
+// together by a single CustomResourceDefinition.
+var ErrInvalidResource = errors.New("invalid resource")
+
+// CustomResourceDefinition mirrors the apiextensions.k8s.io/v1 type of the
+// same name.
+type CustomResourceDefinition struct {
+	APIVersion string                       `yaml:"apiVersion"`
+	Kind       string                       `yaml:"kind"`
+	Metadata   ObjectMeta                   `yaml:"metadata"`
+	Spec       CustomResourceDefinitionSpec `yaml:"spec"`
+}
+
+// ObjectMeta holds the metadata of the CustomResourceDefinition.
+type ObjectMeta struct {
+	Name string `yaml:"name"`
+}
+
+// CustomResourceDefinitionSpec describes how the resource is surfaced.
+type CustomResourceDefinitionSpec struct {
+	Group    string                            `yaml:"group"`
+	Names    CustomResourceDefinitionNames     `yaml:"names"`
+	Scope    string                            `yaml:"scope"`
+	Versions []CustomResourceDefinitionVersion `yaml:"versions"`
+}
+
+// CustomResourceDefinitionNames holds the names of the resource.
+type CustomResourceDefinitionNames struct {
+	Plural     string   `yaml:"plural"`
+	Singular   string   `yaml:"singular,omitempty"`
+	ShortNames []string `yaml:"shortNames,omitempty"`
+	Kind       string   `yaml:"kind"`
+	ListKind   string   `yaml:"listKind,omitempty"`
+	Categories []string `yaml:"categories,omitempty"`
+}
+
+// CustomResourceDefinitionVersion describes a version of the resource.
+type CustomResourceDefinitionVersion struct {
+	Name                     string                           `yaml:"name"`
+	Served                   bool                             `yaml:"served"`
+	Storage                  bool                             `yaml:"storage"`
+	Schema                   *CustomResourceValidation        `yaml:"schema,omitempty"`
+	Subresources             *CustomResourceSubresources      `yaml:"subresources,omitempty"`
+	AdditionalPrinterColumns []CustomResourceColumnDefinition `yaml:"additionalPrinterColumns,omitempty"`
+}
+
+// CustomResourceValidation holds the schema of a version of the resource.
+type CustomResourceValidation struct {
+	OpenAPIV3Schema *JSONSchemaProps `yaml:"openAPIV3Schema,omitempty"`
+}
+
+// CustomResourceColumnDefinition describes a column printed by kubectl get.
+type CustomResourceColumnDefinition struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	Format      string `yaml:"format,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Priority    int32  `yaml:"priority,omitempty"`
+	JSONPath    string `yaml:"jsonPath"`
+}
+
+// CustomResourceSubresources lists the subresources of a version.
+type CustomResourceSubresources struct {
+	Status *CustomResourceSubresourceStatus `yaml:"status,omitempty"`
+	Scale  *CustomResourceSubresourceScale  `yaml:"scale,omitempty"`
+}
+
+// CustomResourceSubresourceStatus enables the status subresource.
+type CustomResourceSubresourceStatus struct{}
+
+// CustomResourceSubresourceScale enables the scale subresource.
+type CustomResourceSubresourceScale struct {
+	SpecReplicasPath   string  `yaml:"specReplicasPath"`
+	StatusReplicasPath string  `yaml:"statusReplicasPath"`
+	LabelSelectorPath  *string `yaml:"labelSelectorPath,omitempty"`
+}
+
+// Resource describes how a version of a kind is served as a custom resource.
+// The scope and the names are shared by all of the versions.
+type Resource struct {
+	// Scope is either NamespaceScoped or ClusterScoped. Empty means
+	// NamespaceScoped.
+	Scope string
+	// Names are the plural, singular and short names, and the categories.
+	// The empty names are derived from the Kind.
+	Names CustomResourceDefinitionNames
+	// Storage marks the version persisted in etcd. Without it, the most
+	// stable version is stored.
+	Storage        bool
+	PrinterColumns []CustomResourceColumnDefinition
+	Subresources   *CustomResourceSubresources
+}
+
+// Version is a version of a kind to include in the CustomResourceDefinition.
+type Version struct {
+	Name     string
+	Type     reflect.Type
+	Resource Resource
+}
+
+// GenerateCRD generates the CustomResourceDefinition serving the versions of
+// the kind. The versions are expected from the most to the least stable one.
+func GenerateCRD(group, kind string, versions ...Version) (CustomResourceDefinition, error) {
+	crd := CustomResourceDefinition{
+		APIVersion: "apiextensions.k8s.io/v1",
+		Kind:       "CustomResourceDefinition",
+		Spec: CustomResourceDefinitionSpec{
+			Group: group,
+			Names: CustomResourceDefinitionNames{
+				Kind:     kind,
+				ListKind: kind + "List",
+			},
+		},
+	}
+	if len(versions) == 0 {
+		return crd, fmt.Errorf("%w: %s.%s has no versions", ErrInvalidResource, kind, group)
+	}
+	storage := -1
+	for i, v := range versions {
+		res := v.Resource
+		if err := mergeNames(&crd.Spec.Names, res.Names); err != nil {
+			return crd, fmt.Errorf("%w: %s.%s version %s: %w", ErrInvalidResource, kind, group, v.Name, err)
+		}
+		if res.Scope != "" && crd.Spec.Scope != "" && res.Scope != crd.Spec.Scope {
+			return crd, fmt.Errorf("%w: %s.%s version %s: scope %s conflicts with %s",
+				ErrInvalidResource, kind, group, v.Name, res.Scope, crd.Spec.Scope)
+		}
+		if res.Scope != "" {
+			crd.Spec.Scope = res.Scope
+		}
+		if res.Storage {
+			if storage >= 0 {
+				return crd, fmt.Errorf("%w: %s.%s has many storage versions: %s and %s",
+					ErrInvalidResource, kind, group, versions[storage].Name, v.Name)
+			}
+			storage = i
+		}
+		s := GenerateForType(v.Type)
+		crd.Spec.Versions = append(crd.Spec.Versions, CustomResourceDefinitionVersion{
+			Name:                     v.Name,
+			Served:                   true,
+			Schema:                   &CustomResourceValidation{OpenAPIV3Schema: &s},
+			Subresources:             res.Subresources,
+			AdditionalPrinterColumns: res.PrinterColumns,
+		})
+	}
+	if storage < 0 {
+		storage = 0
+	}
+	crd.Spec.Versions[storage].Storage = true
+	if crd.Spec.Scope == "" {
+		crd.Spec.Scope = NamespaceScoped
+	}
+	if crd.Spec.Names.Singular == "" {
+		crd.Spec.Names.Singular = strings.ToLower(kind)
+	}
+	if crd.Spec.Names.Plural == "" {
+		crd.Spec.Names.Plural = pluralize(crd.Spec.Names.Singular)
+	}
+	crd.Metadata.Name = crd.Spec.Names.Plural + "." + group
+	return crd, nil
+}
+
+func mergeNames(names *CustomResourceDefinitionNames, other CustomResourceDefinitionNames) error {
+	for _, n := range []struct {
+		what        string
+		name, other *string
+	}{
+		{"plural", &names.Plural, &other.Plural},
+		{"singular", &names.Singular, &other.Singular},
+	} {
+		if *n.other != "" && *n.name != "" && *n.other != *n.name {
+			return fmt.Errorf("%s name %s conflicts with %s", n.what, *n.other, *n.name)
+		}
+		if *n.other != "" {
+			*n.name = *n.other
+		}
+	}
+	names.ShortNames = appendMissing(names.ShortNames, other.ShortNames...)
+	names.Categories = appendMissing(names.Categories, other.Categories...)
+	return nil
+}
+
+func appendMissing(list []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, l := range list {
+			found = found || l == item
+		}
+		if !found {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// pluralize follows the simple English rules, the irregular plurals should be
+// given explicitly.
+func pluralize(singular string) string {
+	switch {
+	case strings.HasSuffix(singular, "s"), strings.HasSuffix(singular, "x"),
+		strings.HasSuffix(singular, "ch"), strings.HasSuffix(singular, "sh"):
+		return singular + "es"
+	case strings.HasSuffix(singular, "y") && len(singular) > 1 &&
+		!strings.ContainsAny(singular[len(singular)-2:len(singular)-1], "aeiou"):
+		return singular[:len(singular)-1] + "ies"
+	default:
+		return singular + "s"
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type policy struct {
+	Replicas int32 `json:"replicas"`
+}
+
+func TestGenerateCRD(t *testing.T) {
+	typ := reflect.TypeOf(policy{})
+	crd, err := GenerateCRD("example.knative.dev", "Policy",
+		Version{Name: "v1", Type: typ, Resource: Resource{
+			Names: CustomResourceDefinitionNames{ShortNames: []string{"pol"}},
+		}},
+		Version{Name: "v1beta1", Type: typ, Resource: Resource{
+			Scope:   ClusterScoped,
+			Storage: true,
+			Names:   CustomResourceDefinitionNames{ShortNames: []string{"pol", "po"}},
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crd.Metadata.Name != "policies.example.knative.dev" {
+		t.Errorf("name = %q", crd.Metadata.Name)
+	}
+	if crd.Spec.Scope != ClusterScoped {
+		t.Errorf("scope = %q", crd.Spec.Scope)
+	}
+	if want := []string{"pol", "po"}; !reflect.DeepEqual(want, crd.Spec.Names.ShortNames) {
+		t.Errorf("short names = %v, want %v", crd.Spec.Names.ShortNames, want)
+	}
+	if crd.Spec.Versions[0].Storage || !crd.Spec.Versions[1].Storage {
+		t.Errorf("v1beta1 should be the only storage version: %+v", crd.Spec.Versions)
+	}
+
+	crd, err = GenerateCRD("example.knative.dev", "Policy", Version{Name: "v1", Type: typ})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !crd.Spec.Versions[0].Storage || crd.Spec.Scope != NamespaceScoped {
+		t.Errorf("the defaults weren't applied: %+v", crd.Spec)
+	}
+
+	_, err = GenerateCRD("example.knative.dev", "Policy",
+		Version{Name: "v1", Type: typ, Resource: Resource{Storage: true}},
+		Version{Name: "v1beta1", Type: typ, Resource: Resource{Storage: true}},
+	)
+	if !errors.Is(err, ErrInvalidResource) {
+		t.Errorf("many storage versions, got err = %v", err)
+	}
+}
+
+func TestParseMarker(t *testing.T) {
+	var res Resource
+	for _, m := range []string{
+		"kubebuilder:resource:scope=Cluster,path=people,singular=person,shortName=p;pe",
+		"kubebuilder:subresource:status",
+		"kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas",
+		`kubebuilder:printcolumn:name="Ready, or not",type=string,JSONPath=".status.ready",priority=1`,
+		"genclient",
+	} {
+		if err := res.parseMarker(m); err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+	}
+	want := Resource{
+		Scope: ClusterScoped,
+		Names: CustomResourceDefinitionNames{
+			Plural:     "people",
+			Singular:   "person",
+			ShortNames: []string{"p", "pe"},
+		},
+		PrinterColumns: []CustomResourceColumnDefinition{{
+			Name:     "Ready, or not",
+			Type:     "string",
+			JSONPath: ".status.ready",
+			Priority: 1,
+		}},
+		Subresources: &CustomResourceSubresources{
+			Status: &CustomResourceSubresourceStatus{},
+			Scale: &CustomResourceSubresourceScale{
+				SpecReplicasPath:   ".spec.replicas",
+				StatusReplicasPath: ".status.replicas",
+			},
+		},
+	}
+	if !reflect.DeepEqual(want, res) {
+		t.Errorf("got %+v, want %+v", res, want)
+	}
+	if err := res.parseMarker("kubebuilder:resource:foo=bar"); err == nil {
+		t.Error("unknown argument should fail")
+	}
+	if err := res.parseMarker("kubebuilder:resource:scope=cluster"); err == nil {
+		t.Error("unknown scope should fail")
+	}
+}
+
+func TestPluralize(t *testing.T) {
+	for singular, plural := range map[string]string{
+		"policy":  "policies",
+		"gateway": "gateways",
+		"broker":  "brokers",
+		"ingress": "ingresses",
+		"box":     "boxes",
+	} {
+		if got := pluralize(singular); got != plural {
+			t.Errorf("pluralize(%q) = %q, want %q", singular, got, plural)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoMatchingVersion is returned when none of the versions of the
+// CustomResourceDefinitions in the manifest has a known type.
+var ErrNoMatchingVersion = errors.New("no matching version")
+
+// TypeLookup returns the type of the group, version and Kind, or nil.
+type TypeLookup func(group, version, kind string) reflect.Type
+
+// UpdateManifest replaces the schema.openAPIV3Schema of the versions of the
+// CustomResourceDefinitions in the YAML manifest, which types are known. Only
+// the lines of the schemas are replaced, the rest of the manifest is kept
+// byte for byte. The updated manifest is returned, and whether it differs
+// from the given one.
+func UpdateManifest(manifest []byte, typeFor TypeLookup) ([]byte, bool, error) {
+	docs, err := decodeDocuments(manifest)
+	if err != nil {
+		return nil, false, err
+	}
+	text := string(manifest)
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	lines := strings.SplitAfter(text, "\n")
+	var edits []lineEdit
+	for _, doc := range docs {
+		docEdits, err := schemaEdits(lines, doc, typeFor)
+		if err != nil {
+			return nil, false, err
+		}
+		edits = append(edits, docEdits...)
+	}
+	if len(edits) == 0 {
+		return nil, false, fmt.Errorf("%w: the manifest has no CustomResourceDefinition "+
+			"with a registered version", ErrNoMatchingVersion)
+	}
+	// the edits are applied from the bottom, so the line numbers stay valid
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	for _, e := range edits {
+		lines = append(lines[:e.start], append(e.lines, lines[e.end:]...)...)
+	}
+	updated := []byte(strings.Join(lines, ""))
+	if !bytes.HasSuffix(manifest, []byte("\n")) {
+		updated = bytes.TrimSuffix(updated, []byte("\n"))
+	}
+	return updated, !bytes.Equal(manifest, updated), nil
+}
+
+// lineEdit replaces the lines from start up to the end, exclusive, of the
+// manifest. The lines are zero indexed, and end with a new line.
+type lineEdit struct {
+	start, end int
+	lines      []string
+}
+
+func decodeDocuments(manifest []byte) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		doc := &yaml.Node{}
+		if err := dec.Decode(doc); errors.Is(err, io.EOF) {
+			return docs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("can't parse the manifest: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// schemaEdits returns the edits replacing the schemas of the known versions,
+// if the document is a CustomResourceDefinition.
+func schemaEdits(lines []string, doc *yaml.Node, typeFor TypeLookup) ([]lineEdit, error) {
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if scalar(mappingValue(root, "kind")) != "CustomResourceDefinition" {
+		return nil, nil
+	}
+	spec := mappingValue(root, "spec")
+	group := scalar(mappingValue(spec, "group"))
+	kind := scalar(mappingValue(mappingValue(spec, "names"), "kind"))
+	versions := mappingValue(spec, "versions")
+	if versions == nil || versions.Kind != yaml.SequenceNode {
+		return nil, nil
+	}
+	var edits []lineEdit
+	for _, version := range versions.Content {
+		name := mappingKey(version, "name")
+		t := typeFor(group, scalar(mappingValue(version, "name")), kind)
+		if t == nil || name == nil {
+			continue
+		}
+		s, err := encodeSchema(t)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, versionEdit(lines, version, name, s))
+	}
+	return edits, nil
+}
+
+// versionEdit replaces the value of the openAPIV3Schema, or adds the missing
+// keys to the version.
+func versionEdit(lines []string, version, name *yaml.Node, s []string) lineEdit {
+	validationKey := mappingKey(version, "schema")
+	if validationKey == nil {
+		indent := name.Column - 1
+		block := append([]string{pad(indent) + "schema:\n", pad(indent+2) + "openAPIV3Schema:\n"},
+			indentLines(s, indent+4)...)
+		end := valueEnd(lines, name)
+		return lineEdit{start: end, end: end, lines: block}
+	}
+	validation := mappingValue(version, "schema")
+	if schemaKey := mappingKey(validation, "openAPIV3Schema"); schemaKey != nil {
+		return replaceValue(lines, schemaKey, mappingValue(validation, "openAPIV3Schema"),
+			indentLines(s, schemaKey.Column+1))
+	}
+	indent := validationKey.Column + 1
+	if isBlock(validationKey, validation) {
+		indent = validation.Content[0].Column - 1
+		at := validationKey.Line
+		return lineEdit{start: at, end: at, lines: append(
+			[]string{pad(indent) + "openAPIV3Schema:\n"}, indentLines(s, indent+2)...)}
+	}
+	return replaceValue(lines, validationKey, validation, append(
+		[]string{pad(indent) + "openAPIV3Schema:\n"}, indentLines(s, indent+2)...))
+}
+
+// replaceValue replaces the value of the key with the block of lines,
+// indented under the key.
+func replaceValue(lines []string, key, value *yaml.Node, block []string) lineEdit {
+	start := key.Line // the line after the key
+	end := valueEnd(lines, key)
+	if !isBlock(key, value) {
+		// the value starts on the line of the key, which is rewritten
+		start = key.Line - 1
+		keyLine := strings.TrimRight(lines[start], "\r\n")
+		if value.Line == key.Line && value.Column-1 <= len(keyLine) {
+			keyLine = keyLine[:value.Column-1]
+		}
+		block = append([]string{strings.TrimRight(keyLine, " ") + "\n"}, block...)
+	}
+	return lineEdit{start: start, end: end, lines: block}
+}
+
+// valueEnd returns the index of the line after the value of the key. The
+// value spans the lines indented deeper than the key, without the trailing
+// blank and comment lines.
+func valueEnd(lines []string, key *yaml.Node) int {
+	end := key.Line
+	for i := key.Line; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(lines[i]) - len(strings.TrimLeft(lines[i], " "))
+		if indent <= key.Column-1 {
+			break
+		}
+		end = i + 1
+	}
+	return end
+}
+
+func isBlock(key, value *yaml.Node) bool {
+	return value != nil && value.Line > key.Line &&
+		(value.Kind == yaml.MappingNode || value.Kind == yaml.SequenceNode) &&
+		value.Style&yaml.FlowStyle == 0 && len(value.Content) > 0
+}
+
+func encodeSchema(t reflect.Type) ([]string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(GenerateForType(t)); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n"), nil
+}
+
+func indentLines(lines []string, indent int) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = pad(indent) + strings.TrimSuffix(l, "\n") + "\n"
+	}
+	return out
+}
+
+func pad(indent int) string {
+	return strings.Repeat(" ", indent)
+}
+
+func mappingKey(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i]
+		}
+	}
+	return nil
+}
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalar(n *yaml.Node) string {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// manifest follows the knative style, which differs from the output of the
+// yaml encoder, to ensure the rest of the manifest is kept as is.
+const manifest = `# Copyright 2026 The Knative Authors
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: policies.example.knative.dev
+  labels:
+    knative.dev/crd-install: "true"
+spec:
+  group: example.knative.dev
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    # generated
+    schema:
+      openAPIV3Schema:
+        type: object
+        x-kubernetes-preserve-unknown-fields: true
+
+    additionalPrinterColumns:
+    - name: Ready
+      type: string
+      jsonPath: ".status.conditions[?(@.type=='Ready')].status"
+
+  - name: v1alpha1
+    served: false
+    storage: false
+  names:
+    kind: Policy
+    plural: policies
+    categories: [all, knative]
+  scope: Namespaced
+  conversion:
+    strategy: Webhook # hand-maintained
+    webhook:
+      conversionReviewVersions: ["v1", "v1beta1"]
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config-policy
+`
+
+// emptyPolicy has no fields, so the schema doesn't depend on the docs.
+type emptyPolicy struct{}
+
+func policyType(group, version, kind string) reflect.Type {
+	if group == "example.knative.dev" && kind == "Policy" && version == "v1" {
+		return reflect.TypeOf(emptyPolicy{})
+	}
+	return nil
+}
+
+func TestUpdateManifest(t *testing.T) {
+	updated, changed, err := UpdateManifest([]byte(manifest), policyType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("the schema should have changed")
+	}
+	want := strings.Replace(manifest, `        x-kubernetes-preserve-unknown-fields: true
+`, "", 1)
+	if got := string(updated); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	again, changed, err := UpdateManifest(updated, policyType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || string(again) != string(updated) {
+		t.Errorf("the update should be stable, got:\n%s", again)
+	}
+}
+
+func TestUpdateManifestAddsSchema(t *testing.T) {
+	lookup := func(group, version, kind string) reflect.Type {
+		return policyType(group, strings.Replace(version, "v1alpha1", "v1", 1), kind)
+	}
+	updated, _, err := UpdateManifest([]byte(manifest), lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(manifest, `  - name: v1alpha1
+`, `  - name: v1alpha1
+    schema:
+      openAPIV3Schema:
+        type: object
+`, 1)
+	want = strings.Replace(want, `        x-kubernetes-preserve-unknown-fields: true
+`, "", 1)
+	if got := string(updated); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpdateManifestFlowSchema(t *testing.T) {
+	flow := strings.Replace(manifest, `      openAPIV3Schema:
+        type: object
+        x-kubernetes-preserve-unknown-fields: true
+`, `      openAPIV3Schema: {type: object,
+        x-kubernetes-preserve-unknown-fields: true}
+`, 1)
+	updated, changed, err := UpdateManifest([]byte(flow), policyType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(manifest, `        x-kubernetes-preserve-unknown-fields: true
+`, "", 1)
+	if got := string(updated); !changed || got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpdateManifestNoMatchingVersion(t *testing.T) {
+	none := func(string, string, string) reflect.Type { return nil }
+	if _, _, err := UpdateManifest([]byte(manifest), none); !errors.Is(err, ErrNoMatchingVersion) {
+		t.Errorf("got err = %v, want %v", err, ErrNoMatchingVersion)
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"knative.dev/hack/schema/docs"
+)
+
+// ErrInvalidMarker is returned for the markers that can't be parsed.
+var ErrInvalidMarker = errors.New("invalid marker")
+
+const markerPrefix = "kubebuilder:"
+
+// ResourceForType reads the Resource from the kubebuilder markers in the doc
+// comment of the type. The following markers are understood:
+//
+//	+kubebuilder:resource:scope=Cluster,path=foos,singular=foo,shortName=f;fo,categories=all;knative
+//	+kubebuilder:storageversion
+//	+kubebuilder:subresource:status
+//	+kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+//	+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=".status.ready",priority=1
+//
+// The types, which sources can't be found, have no markers.
+func ResourceForType(t reflect.Type) (Resource, error) {
+	var res Resource
+	markers, err := docs.GetMarkersForType(t)
+	if err != nil {
+		return res, nil //nolint:nilerr // same as the missing docs of the fields
+	}
+	for _, m := range markers {
+		if err = res.parseMarker(m); err != nil {
+			return res, fmt.Errorf("%w: %s: +%s: %w", ErrInvalidMarker, t.Name(), m, err)
+		}
+	}
+	return res, nil
+}
+
+func (r *Resource) parseMarker(marker string) error {
+	if !strings.HasPrefix(marker, markerPrefix) {
+		return nil
+	}
+	name, args, _ := strings.Cut(strings.TrimPrefix(marker, markerPrefix), ":")
+	switch name {
+	case "storageversion":
+		r.Storage = true
+	case "resource":
+		return parseMarkerArgs(args, func(key, value string) error {
+			switch key {
+			case "scope":
+				if value != NamespaceScoped && value != ClusterScoped {
+					return fmt.Errorf("scope %q, want %s or %s", value, NamespaceScoped, ClusterScoped)
+				}
+				r.Scope = value
+			case "path":
+				r.Names.Plural = value
+			case "singular":
+				r.Names.Singular = value
+			case "shortName":
+				r.Names.ShortNames = strings.Split(value, ";")
+			case "categories":
+				r.Names.Categories = strings.Split(value, ";")
+			default:
+				return fmt.Errorf("unknown argument %q", key)
+			}
+			return nil
+		})
+	case "subresource":
+		return r.parseSubresource(args)
+	case "printcolumn":
+		var col CustomResourceColumnDefinition
+		err := parseMarkerArgs(args, func(key, value string) error {
+			switch key {
+			case "name":
+				col.Name = value
+			case "type":
+				col.Type = value
+			case "format":
+				col.Format = value
+			case "description":
+				col.Description = value
+			case "JSONPath":
+				col.JSONPath = value
+			case "priority":
+				p, err := strconv.ParseInt(value, 10, 32)
+				col.Priority = int32(p)
+				return err
+			default:
+				return fmt.Errorf("unknown argument %q", key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		r.PrinterColumns = append(r.PrinterColumns, col)
+	}
+	return nil
+}
+
+func (r *Resource) parseSubresource(args string) error {
+	if r.Subresources == nil {
+		r.Subresources = &CustomResourceSubresources{}
+	}
+	kind, args, _ := strings.Cut(args, ":")
+	switch kind {
+	case "status":
+		r.Subresources.Status = &CustomResourceSubresourceStatus{}
+	case "scale":
+		scale := &CustomResourceSubresourceScale{}
+		err := parseMarkerArgs(args, func(key, value string) error {
+			switch key {
+			case "specpath":
+				scale.SpecReplicasPath = value
+			case "statuspath":
+				scale.StatusReplicasPath = value
+			case "selectorpath":
+				scale.LabelSelectorPath = &value
+			default:
+				return fmt.Errorf("unknown argument %q", key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		r.Subresources.Scale = scale
+	default:
+		return fmt.Errorf("unknown subresource %q", kind)
+	}
+	return nil
+}
+
+// parseMarkerArgs calls the fn for each of the comma separated key=value
+// arguments. The values may be double-quoted, to contain commas.
+func parseMarkerArgs(args string, fn func(key, value string) error) error {
+	for args != "" {
+		key, rest, ok := strings.Cut(args, "=")
+		if !ok {
+			return fmt.Errorf("missing value of %q", key)
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return fmt.Errorf("value of %q: %w", key, err)
+			}
+			if value, err = strconv.Unquote(quoted); err != nil {
+				return fmt.Errorf("value of %q: %w", key, err)
+			}
+			rest = strings.TrimPrefix(rest[len(quoted):], ",")
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+		args = rest
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"io"
+	"os"
+)
+
+const (
+	// NoColorEnvVar disables the colored output, when set to a non-empty
+	// value. It's honored by gum, used in library.sh, as well.
+	NoColorEnvVar = "NO_COLOR"
+	// ForceColorEnvVar forces the colored output, when set to a value other
+	// than "0". It's honored by gum, used in library.sh, as well.
+	ForceColorEnvVar = "CLICOLOR_FORCE"
+
+	ansiReset    = "\x1b[0m"
+	ansiRed      = "\x1b[31m"
+	ansiGreen    = "\x1b[32m"
+	ansiEscape   = 0x1b
+	ansiBell     = 0x07
+	ansiCSIStart = '['
+	ansiOSCStart = ']'
+)
+
+// ColorMode controls how the ANSI escape sequences in the output of the
+// scripts are handled.
+type ColorMode int
+
+const (
+	// ColorAuto uses ColorAlways if the target writer is a terminal, and
+	// ColorNever otherwise. The NoColorEnvVar and ForceColorEnvVar
+	// environment variables take precedence.
+	ColorAuto ColorMode = iota
+	// ColorNever strips the ANSI escape sequences from the output.
+	ColorNever
+	// ColorKeep passes the ANSI escape sequences through, as is.
+	ColorKeep
+	// ColorAlways passes the ANSI escape sequences through, and colors the
+	// prefix by the stream type.
+	ColorAlways
+)
+
+// resolve returns the effective color mode for the writer.
+func (m ColorMode) resolve(writer io.Writer) ColorMode {
+	if m != ColorAuto {
+		return m
+	}
+	if os.Getenv(NoColorEnvVar) != "" {
+		return ColorNever
+	}
+	if v := os.Getenv(ForceColorEnvVar); v != "" && v != "0" {
+		return ColorAlways
+	}
+	if isTerminal(writer) {
+		return ColorAlways
+	}
+	return ColorNever
+}
+
+func isTerminal(writer io.Writer) bool {
+	if mux, ok := writer.(*LineMux); ok {
+		writer = mux.writer
+	}
+	f, ok := writer.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// colorPrefix wraps the prefix in the color of the stream type.
+func colorPrefix(st StreamType, prefix func() string) func() string {
+	color := ansiGreen
+	if st == StreamTypeErr {
+		color = ansiRed
+	}
+	return func() string {
+		p := prefix()
+		if p == "" {
+			return p
+		}
+		return color + p + ansiReset
+	}
+}
+
+type ansiState int
+
+const (
+	ansiText ansiState = iota
+	ansiEsc
+	ansiCSI
+	ansiOSC
+	ansiOSCEsc
+)
+
+// ansiStripper removes the ANSI escape sequences, that may be split across
+// many writes, before forwarding the payload to the writer.
+type ansiStripper struct {
+	writer io.Writer
+	state  ansiState
+	buf    []byte
+}
+
+func (s *ansiStripper) Write(payload []byte) (int, error) {
+	s.buf = s.buf[:0]
+	for _, b := range payload {
+		switch s.state {
+		case ansiText:
+			if b == ansiEscape {
+				s.state = ansiEsc
+				continue
+			}
+			s.buf = append(s.buf, b)
+		case ansiEsc:
+			switch b {
+			case ansiCSIStart:
+				s.state = ansiCSI
+			case ansiOSCStart:
+				s.state = ansiOSC
+			default:
+				s.state = ansiText
+			}
+		case ansiCSI:
+			// the final byte of the control sequence
+			if b >= 0x40 && b <= 0x7e {
+				s.state = ansiText
+			}
+		case ansiOSC:
+			switch b {
+			case ansiBell:
+				s.state = ansiText
+			case ansiEscape:
+				s.state = ansiOSCEsc
+			}
+		case ansiOSCEsc:
+			if b == '\\' {
+				s.state = ansiText
+			} else {
+				s.state = ansiOSC
+			}
+		}
+	}
+	if len(s.buf) == 0 {
+		return len(payload), nil
+	}
+	if _, err := s.writer.Write(s.buf); err != nil {
+		return 0, err
+	}
+	return len(payload), nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventType represents a type of the execution event.
+type EventType string
+
+const (
+	// EventTypeStart is emitted when the script or function is started.
+	EventTypeStart EventType = "start"
+	// EventTypeLine is emitted for each line of the output or error stream.
+	EventTypeLine EventType = "line"
+	// EventTypeExit is emitted when the script or function has finished.
+	EventTypeExit EventType = "exit"
+	// EventTypeError is emitted, instead of the exit event, when the script or
+	// function couldn't be started.
+	EventTypeError EventType = "error"
+)
+
+// Event is a machine-readable record of the script or function execution.
+type Event struct {
+	Time     time.Time   `json:"time"`
+	Type     EventType   `json:"type"`
+	Label    string      `json:"label"`
+	Stream   *StreamType `json:"stream,omitempty"`
+	Line     string      `json:"line,omitempty"`
+	ExitCode *int        `json:"exitCode,omitempty"`
+	Signal   string      `json:"signal,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// EventSink receives the events of the executed scripts and functions. Emit
+// is called concurrently, for the output and error streams of an execution,
+// and for the parallel jobs of a Group sharing the sink, so the
+// implementations must be safe for concurrent use.
+type EventSink interface {
+	Emit(ev Event)
+}
+
+// NewJSONLinesSink creates an EventSink that writes each event as a single
+// JSON line to the writer. The sink is safe for concurrent use.
+func NewJSONLinesSink(writer io.Writer) EventSink {
+	return &jsonLinesSink{enc: json.NewEncoder(writer)}
+}
+
+type jsonLinesSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (j *jsonLinesSink) Emit(ev Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_ = j.enc.Encode(ev)
+}
+
+// String returns a short name of the stream type.
+func (st StreamType) String() string {
+	switch st {
+	case StreamTypeOut:
+		return "out"
+	case StreamTypeErr:
+		return "err"
+	}
+	return "unknown"
+}
+
+// MarshalText marshals the stream type as its short name.
+func (st StreamType) MarshalText() ([]byte, error) {
+	return []byte(st.String()), nil
+}
+
+// newEventEmitter creates an emitter of the single execution events, or nil
+// if there is no sink configured.
+func newEventEmitter(sink EventSink, label string) *eventEmitter {
+	if sink == nil {
+		return nil
+	}
+	e := &eventEmitter{sink: sink, label: label}
+	e.out = &lineEvents{emitter: e, stream: StreamTypeOut}
+	e.err = &lineEvents{emitter: e, stream: StreamTypeErr}
+	return e
+}
+
+type eventEmitter struct {
+	sink  EventSink
+	label string
+	out   *lineEvents
+	err   *lineEvents
+}
+
+func (e *eventEmitter) start(t time.Time) {
+	if e == nil {
+		return
+	}
+	e.sink.Emit(Event{Time: t, Type: EventTypeStart, Label: e.label})
+}
+
+func (e *eventEmitter) fail(t time.Time, err error) {
+	if e == nil {
+		return
+	}
+	e.sink.Emit(Event{Time: t, Type: EventTypeError, Label: e.label, Error: err.Error()})
+}
+
+func (e *eventEmitter) exit(res Result) {
+	if e == nil {
+		return
+	}
+	e.out.flush()
+	e.err.flush()
+	ev := Event{
+		Time:     res.EndTime,
+		Type:     EventTypeExit,
+		Label:    e.label,
+		ExitCode: &res.ExitCode,
+	}
+	if res.Signal != nil {
+		ev.Signal = res.Signal.String()
+	}
+	e.sink.Emit(ev)
+}
+
+// writer returns a writer that emits line events for the given stream.
+func (e *eventEmitter) writer(st StreamType) io.Writer {
+	if e == nil {
+		return io.Discard
+	}
+	if st == StreamTypeErr {
+		return e.err
+	}
+	return e.out
+}
+
+// lineEvents splits the stream into lines, and emits an event for each of
+// them.
+type lineEvents struct {
+	emitter *eventEmitter
+	stream  StreamType
+	buf     []byte
+}
+
+func (l *lineEvents) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.emit(l.buf[:i])
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush emits the last line, that wasn't terminated with a newline.
+func (l *lineEvents) flush() {
+	if len(l.buf) > 0 {
+		l.emit(l.buf)
+		l.buf = nil
+	}
+}
+
+func (l *lineEvents) emit(line []byte) {
+	st := l.stream
+	l.emitter.sink.Emit(Event{
+		Time:   time.Now(),
+		Type:   EventTypeLine,
+		Label:  l.emitter.label,
+		Stream: &st,
+		Line:   string(line),
+	})
+}
@@ -17,25 +17,66 @@ limitations under the License.
 package shell
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path"
+	"reflect"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"knative.dev/hack/pkg/retcode"
 )
 
 const (
-	defaultLabelOut = "[OUT]"
-	defaultLabelErr = "[ERR]"
-	executeMode     = 0700
+	defaultLabelOut    = "[OUT]"
+	defaultLabelErr    = "[ERR]"
+	defaultGracePeriod = 10 * time.Second
+	executeMode        = 0700
+)
+
+var (
+	// ErrNoProjectLocation is returned if user didnt provided the project location.
+	ErrNoProjectLocation = errors.New("project location isn't provided")
+
+	// ErrTimedOut is returned if the execution didn't finish before the deadline
+	// of the given context.
+	ErrTimedOut = errors.New("execution timed out")
+
+	// ErrCanceled is returned if the given context was canceled before the
+	// execution finished.
+	ErrCanceled = errors.New("execution canceled")
+
+	// ErrNonZeroExit is returned if the executed script or function exited with
+	// a non-zero exit code.
+	ErrNonZeroExit = errors.New("execution exited with non-zero code")
+
+	// ErrInvalidArgument is returned if an argument can't be passed to bash,
+	// as it contains a NUL byte.
+	ErrInvalidArgument = errors.New("argument can't contain a NUL byte")
 )
 
-// ErrNoProjectLocation is returned if user didnt provided the project location.
-var ErrNoProjectLocation = errors.New("project location isn't provided")
+// RetcodeNoProjectLocation is returned for ErrNoProjectLocation. It's the
+// EX_CONFIG of sysexits.h.
+const RetcodeNoProjectLocation = 78
+
+func init() { //nolint:gochecknoinits
+	retcode.Register(retcode.Code{
+		Code: RetcodeNoProjectLocation,
+		Err:  ErrNoProjectLocation,
+		Name: "knative.dev/hack/shell.ErrNoProjectLocation",
+		Description: "The shell.ExecutorConfig doesn't have the ProjectLocation set. " +
+			"Use one of the shell.New*ProjectLocation functions to create it.",
+	})
+}
 
 // NewExecutor creates a new executor from given config.
-func NewExecutor(config ExecutorConfig) Executor {
+func NewExecutor(config ExecutorConfig) ContextExecutor {
 	configureDefaultValues(&config)
 	return &streamingExecutor{
 		ExecutorConfig: config,
@@ -44,26 +85,52 @@ func NewExecutor(config ExecutorConfig) Executor {
 
 // RunScript executes a shell script with args.
 func (s *streamingExecutor) RunScript(script Script, args ...string) error {
+	_, err := s.RunScriptContext(context.Background(), script, args...)
+	return err
+}
+
+// RunFunction executes a shell function with args.
+func (s *streamingExecutor) RunFunction(fn Function, args ...string) error {
+	_, err := s.RunFunctionContext(context.Background(), fn, args...)
+	return err
+}
+
+// RunScriptContext executes a shell script with args. The script is terminated
+// when the context is done.
+func (s *streamingExecutor) RunScriptContext(ctx context.Context, script Script, args ...string) (Result, error) {
 	err := validate(s.ExecutorConfig)
 	if err != nil {
-		return err
+		return Result{}, err
 	}
-	cnt := script.scriptContent(s.ProjectLocation, args)
-	return withTempScript(cnt, func(bin string) error {
-		return stream(bin, s.ExecutorConfig, script.Label)
-	})
+	if err = validateArgs(args); err != nil {
+		return Result{}, err
+	}
+	cnt := script.scriptContent(s.ProjectLocation, args, s.TraceSink != nil)
+	return s.run(ctx, cnt, script)
 }
 
-// RunFunction executes a shell function with args.
-func (s *streamingExecutor) RunFunction(fn Function, args ...string) error {
+// RunFunctionContext executes a shell function with args. The function is
+// terminated when the context is done.
+func (s *streamingExecutor) RunFunctionContext(ctx context.Context, fn Function, args ...string) (Result, error) {
 	err := validate(s.ExecutorConfig)
 	if err != nil {
-		return err
+		return Result{}, err
+	}
+	if err = validateArgs(args); err != nil {
+		return Result{}, err
 	}
-	cnt := fn.scriptContent(s.ProjectLocation, args)
-	return withTempScript(cnt, func(bin string) error {
-		return stream(bin, s.ExecutorConfig, fn.Label)
+	cnt := fn.scriptContent(s.ProjectLocation, args, s.TraceSink != nil)
+	return s.run(ctx, cnt, fn.Script)
+}
+
+func (s *streamingExecutor) run(ctx context.Context, cnt string, script Script) (Result, error) {
+	var res Result
+	err := withTempScript(cnt, func(bin string) error {
+		var err error
+		res, err = stream(ctx, bin, s.ExecutorConfig, script)
+		return err
 	})
+	return res, err
 }
 
 type streamingExecutor struct {
@@ -99,14 +166,223 @@ func configureDefaultValues(config *ExecutorConfig) {
 	if config.PrefixFunc == nil {
 		config.PrefixFunc = defaultPrefixFunc
 	}
+	if config.GracePeriod == 0 {
+		config.GracePeriod = defaultGracePeriod
+	}
 }
 
-func stream(bin string, cfg ExecutorConfig, label string) error {
+func stream(ctx context.Context, bin string, cfg ExecutorConfig, script Script) (Result, error) {
+	outs := newOutputs(cfg, script.Label)
 	c := exec.Command(bin)
-	c.Env = cfg.Environ
-	c.Stdout = NewPrefixer(cfg.Out, prefixFunc(StreamTypeOut, label, cfg))
-	c.Stderr = NewPrefixer(cfg.Err, prefixFunc(StreamTypeErr, label, cfg))
-	return c.Run()
+	c.Env = outs.environ(mergeEnviron(cfg.Environ, script.Environ, script.Unsetenv))
+	if script.Stdin != "" {
+		c.Stdin = strings.NewReader(script.Stdin)
+	}
+	c.Stdout = outs.stdout
+	c.Stderr = outs.stderr
+	startProcessGroup(c)
+	var tc *traceCapture
+	if cfg.TraceSink != nil {
+		var err error
+		if tc, err = startTraceCapture(c); err != nil {
+			return Result{}, err
+		}
+	}
+	res := Result{StartTime: time.Now()}
+	// the start event goes first, as the output is copied as soon as started
+	outs.events.start(res.StartTime)
+	if err := c.Start(); err != nil {
+		res.EndTime = time.Now()
+		outs.events.fail(res.EndTime, err)
+		if tc != nil {
+			tc.close()
+		}
+		return res, err
+	}
+	if tc != nil {
+		tc.started()
+	}
+	g := &processGroup{p: c.Process, exited: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			g.terminate(cfg.GracePeriod)
+		case <-g.exited:
+		}
+	}()
+	err := g.wait(c)
+	res.EndTime = time.Now()
+	res.ExitCode = c.ProcessState.ExitCode()
+	res.Signal = exitSignal(c.ProcessState)
+	outs.finish(&res)
+	var traceErr error
+	if tc != nil {
+		trace := tc.finish()
+		if err != nil || cfg.TraceAlways {
+			if terr := cfg.TraceSink.Trace(script, res, trace); terr != nil {
+				traceErr = fmt.Errorf("can't store the trace of %s: %w", script.Label, terr)
+			}
+		}
+	}
+	return res, errors.Join(executionErr(ctx, script, res, err), traceErr)
+}
+
+// outputs holds the writers of a single script or function execution.
+type outputs struct {
+	stdout     io.Writer
+	stderr     io.Writer
+	captureOut *capture
+	captureErr *capture
+	events     *eventEmitter
+	prefixers  []*LinePrefixer
+	// color is set when the escape sequences are passed through
+	color bool
+}
+
+func newOutputs(cfg ExecutorConfig, label string) *outputs {
+	o := &outputs{
+		captureOut: newCapture(cfg.CaptureLimit),
+		captureErr: newCapture(cfg.CaptureLimit),
+		events:     newEventEmitter(cfg.Events, label),
+	}
+	o.stdout = io.MultiWriter(
+		o.prefixer(cfg, cfg.Out, StreamTypeOut, label),
+		o.captureOut, o.events.writer(StreamTypeOut))
+	o.stderr = io.MultiWriter(
+		o.prefixer(cfg, cfg.Err, StreamTypeErr, label),
+		o.captureErr, o.events.writer(StreamTypeErr))
+	return o
+}
+
+func (o *outputs) prefixer(cfg ExecutorConfig, w io.Writer, st StreamType, label string) io.Writer {
+	prefix := prefixFunc(st, label, cfg)
+	switch cfg.Color.resolve(w) {
+	case ColorNever:
+		return &ansiStripper{writer: o.linePrefixer(cfg, w, prefix)}
+	case ColorAlways:
+		prefix = colorPrefix(st, prefix)
+		o.color = true
+	case ColorKeep, ColorAuto:
+	}
+	return o.linePrefixer(cfg, w, prefix)
+}
+
+func (o *outputs) linePrefixer(cfg ExecutorConfig, w io.Writer, prefix func() string) io.Writer {
+	if !cfg.LineBuffered {
+		return NewPrefixer(w, prefix)
+	}
+	mux, ok := w.(*LineMux)
+	if !ok {
+		for _, lp := range o.prefixers {
+			if sameWriter(lp.mux.writer, w) {
+				mux = lp.mux
+			}
+		}
+	}
+	if mux == nil {
+		mux = NewLineMux(w, cfg.LineOptions)
+	}
+	lp := mux.Prefixer(prefix)
+	o.prefixers = append(o.prefixers, lp)
+	return lp
+}
+
+func sameWriter(a, b io.Writer) bool {
+	ta := reflect.TypeOf(a)
+	return ta == reflect.TypeOf(b) && ta != nil && ta.Comparable() && a == b
+}
+
+// environ forces the colored output of the tools, like gum, that don't see
+// a terminal on their output, if the escape sequences are passed through.
+func (o *outputs) environ(env []string) []string {
+	if !o.color {
+		return env
+	}
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if name == NoColorEnvVar || name == ForceColorEnvVar {
+			return env
+		}
+	}
+	return append(env[:len(env):len(env)], ForceColorEnvVar+"=1")
+}
+
+// finish stores the captured output in the result, and emits the exit event.
+func (o *outputs) finish(res *Result) {
+	for _, lp := range o.prefixers {
+		_ = lp.Close()
+	}
+	res.Stdout = o.captureOut.Bytes()
+	res.Stderr = o.captureErr.Bytes()
+	o.events.exit(*res)
+}
+
+// processGroup is the process group led by a started command. Once the
+// leader is reaped its ID may be reused, so the group isn't signalled anymore.
+type processGroup struct {
+	p      *os.Process
+	exited chan struct{}
+	mu     sync.Mutex
+	reaped bool
+}
+
+// wait waits for the command, and marks the group as reaped before closing
+// the exited channel.
+func (g *processGroup) wait(c *exec.Cmd) error {
+	err := c.Wait()
+	g.mu.Lock()
+	g.reaped = true
+	g.mu.Unlock()
+	close(g.exited)
+	return err
+}
+
+func (g *processGroup) signal(sig syscall.Signal) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.reaped {
+		_ = signalProcessGroup(g.p, sig)
+	}
+}
+
+// terminate sends SIGTERM to the process group, and if the processes are
+// still running after the grace period, it sends the SIGKILL to them.
+func (g *processGroup) terminate(gracePeriod time.Duration) {
+	g.signal(sigTerm)
+	select {
+	case <-g.exited:
+	case <-time.After(gracePeriod):
+		g.signal(sigKill)
+	}
+}
+
+func executionErr(ctx context.Context, script Script, res Result, err error) error {
+	if err == nil {
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	return &ExitError{
+		Label:      script.Label,
+		ScriptPath: script.ScriptPath,
+		Result:     res,
+		Cause:      failureCause(ctx, ErrNonZeroExit),
+		Err:        err,
+	}
+}
+
+// failureCause returns the cause of the failed execution, based on the state
+// of the context, or the fallback if the context isn't done.
+func failureCause(ctx context.Context, fallback error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return ErrTimedOut
+	case errors.Is(ctx.Err(), context.Canceled):
+		return ErrCanceled
+	}
+	return fallback
 }
 
 func prefixFunc(st StreamType, label string, cfg ExecutorConfig) func() string {
@@ -133,56 +409,150 @@ func defaultPrefixFunc(st StreamType, label string, cfg ExecutorConfig) string {
 }
 
 func withTempScript(contents string, fn func(bin string) error) error {
-	tmpfile, err := os.CreateTemp("", "shellout-*.sh")
+	bin, err := writeTempScript(contents)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		// clean up
+		_ = os.Remove(bin)
+	}()
+
+	return fn(bin)
+}
+
+func writeTempScript(contents string) (string, error) {
+	return writeTempFile("shellout-*.sh", contents, executeMode)
+}
+
+func writeTempFile(pattern, contents string, mode os.FileMode) (string, error) {
+	tmpfile, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
 	_, err = tmpfile.WriteString(contents)
 	if err != nil {
-		return err
+		return "", err
 	}
-	err = tmpfile.Chmod(executeMode)
+	err = tmpfile.Chmod(mode)
 	if err != nil {
-		return err
+		return "", err
 	}
 	err = tmpfile.Close()
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer func() {
-		// clean up
-		_ = os.Remove(tmpfile.Name())
-	}()
-
-	return fn(tmpfile.Name())
+	return tmpfile.Name(), nil
 }
 
-func (fn *Function) scriptContent(location ProjectLocation, args []string) string {
+func (fn *Function) scriptContent(location ProjectLocation, args []string, trace bool) string {
 	return fmt.Sprintf(`#!/usr/bin/env bash
 
 set -Eeuo pipefail
-
-cd "%s"
+%s
+cd %s
 source %s
+%scd %s
 
 %s %s
-`, location.RootPath(), fn.ScriptPath, fn.FunctionName, quoteArgs(args))
+`, prelude(trace), shellQuote(location.RootPath()), shellQuote(fn.ScriptPath), xtrace(trace),
+		shellQuote(fn.workDir(location)), shellQuote(fn.FunctionName), quoteArgs(args))
 }
 
-func (sc *Script) scriptContent(location ProjectLocation, args []string) string {
+func (sc *Script) scriptContent(location ProjectLocation, args []string, trace bool) string {
 	return fmt.Sprintf(`#!/usr/bin/env bash
 
 set -Eeuo pipefail
+%s
+cd %s
+%s
+`, prelude(trace), shellQuote(sc.workDir(location)),
+		scriptCall(trace, shellQuote(sc.command(location))+" "+quoteArgs(args)))
+}
 
-cd "%s"
-%s %s
-`, location.RootPath(), sc.ScriptPath, quoteArgs(args))
+// prelude returns the commands run before any other in the generated script.
+func prelude(trace bool) string {
+	if trace {
+		return tracePrelude
+	}
+	return ""
+}
+
+// xtrace returns the command turning the trace on, after the setup of the
+// generated script.
+func xtrace(trace bool) string {
+	if trace {
+		return "set -x\n"
+	}
+	return ""
+}
+
+// scriptCall returns the call of the script. When traced, the xtrace option
+// is passed to the script through the exported SHELLOPTS, without the strict
+// mode options of the generated script.
+func scriptCall(trace bool, call string) string {
+	if trace {
+		return "set -x\n(set +Eeu +o pipefail; export SHELLOPTS; " + call + ")"
+	}
+	return call
+}
+
+func (sc *Script) workDir(location ProjectLocation) string {
+	return path.Join(location.RootPath(), sc.Dir)
+}
+
+// command returns the script path, that is resolved against the project root,
+// if the script is run from other working directory.
+func (sc *Script) command(location ProjectLocation) string {
+	if sc.Dir == "" || !strings.Contains(sc.ScriptPath, "/") || path.IsAbs(sc.ScriptPath) {
+		return sc.ScriptPath
+	}
+	return path.Join(location.RootPath(), sc.ScriptPath)
+}
+
+// mergeEnviron returns the base environment without the unset variables, and
+// with the overlay variables set over it.
+func mergeEnviron(base, overlay, unset []string) []string {
+	if len(overlay) == 0 && len(unset) == 0 {
+		return base
+	}
+	drop := make(map[string]bool, len(overlay)+len(unset))
+	for _, name := range unset {
+		drop[name] = true
+	}
+	for _, kv := range overlay {
+		name, _, _ := strings.Cut(kv, "=")
+		drop[name] = true
+	}
+	env := make([]string, 0, len(base)+len(overlay))
+	for _, kv := range base {
+		if name, _, _ := strings.Cut(kv, "="); !drop[name] {
+			env = append(env, kv)
+		}
+	}
+	return append(env, overlay...)
 }
 
 func quoteArgs(args []string) string {
 	quoted := make([]string, len(args))
 	for i, arg := range args {
-		quoted[i] = "\"" + strings.ReplaceAll(arg, "\"", "\\\"") + "\""
+		quoted[i] = shellQuote(arg)
 	}
 	return strings.Join(quoted, " ")
 }
+
+// shellQuote puts the string in single quotes, so bash will not interpret any
+// of its characters. The single quotes within the string are closed, escaped
+// and reopened.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func validateArgs(args []string) error {
+	for _, arg := range args {
+		if strings.IndexByte(arg, 0) >= 0 {
+			return fmt.Errorf("%w: %q", ErrInvalidArgument, arg)
+		}
+	}
+	return nil
+}
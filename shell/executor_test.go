@@ -18,7 +18,16 @@ package shell_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
 	"testing"
+	"testing/quick"
+	"time"
 
 	"knative.dev/hack/shell"
 )
@@ -63,6 +72,387 @@ func TestExecutorDefaults(t *testing.T) {
 	assert.NoError(err)
 }
 
+func TestExecutorContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		script shell.Script
+		args   []string
+		ctx    func() (context.Context, context.CancelFunc)
+		want   error
+	}{{
+		name:   "timeout",
+		script: shell.Script{Label: "sleep", ScriptPath: "sleep"},
+		args:   []string{"10"},
+		ctx: func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 100*time.Millisecond)
+		},
+		want: shell.ErrTimedOut,
+	}, {
+		name:   "canceled",
+		script: shell.Script{Label: "sleep", ScriptPath: "sleep"},
+		args:   []string{"10"},
+		ctx: func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(100*time.Millisecond, cancel)
+			return ctx, cancel
+		},
+		want: shell.ErrCanceled,
+	}, {
+		name:   "ignored SIGTERM",
+		script: shell.Script{Label: "trap", ScriptPath: "bash"},
+		args:   []string{"-c", "trap '' TERM; sleep 10"},
+		ctx: func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 100*time.Millisecond)
+		},
+		want: shell.ErrTimedOut,
+	}, {
+		name:   "non-zero exit",
+		script: shell.Script{Label: "false", ScriptPath: "false"},
+		ctx: func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 10*time.Second)
+		},
+		want: shell.ErrNonZeroExit,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config(t, func(cfg *shell.ExecutorConfig) {
+				cfg.Out = &bytes.Buffer{}
+				cfg.Err = &bytes.Buffer{}
+				cfg.GracePeriod = 100 * time.Millisecond
+			})
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			start := time.Now()
+			_, err := shell.NewExecutor(cfg).RunScriptContext(ctx, tt.script, tt.args...)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("want: %v\n got: %v", tt.want, err)
+			}
+			if d := time.Since(start); d > 5*time.Second {
+				t.Errorf("execution wasn't terminated in time, took: %v", d)
+			}
+		})
+	}
+}
+
+func TestExecutorResult(t *testing.T) {
+	assert := assertions{t: t}
+	var outB, errB bytes.Buffer
+	cfg := config(t, func(cfg *shell.ExecutorConfig) {
+		cfg.Out = &outB
+		cfg.Err = &errB
+		cfg.SkipDate = true
+		cfg.CaptureLimit = 4
+	})
+	script := shell.Script{Label: "result", ScriptPath: "bash"}
+	res, err := shell.NewExecutor(cfg).RunScriptContext(context.Background(),
+		script, "-c", "echo 123456; echo abc >&2; exit 3")
+	var exitErr *shell.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("want *shell.ExitError, got: %#v", err)
+	}
+	if !errors.Is(err, shell.ErrNonZeroExit) {
+		t.Errorf("want %v, got: %v", shell.ErrNonZeroExit, err)
+	}
+	assert.Equal("result", exitErr.Label)
+	assert.Equal("bash", exitErr.ScriptPath)
+	if res.ExitCode != 3 || exitErr.ExitCode != 3 {
+		t.Errorf("want exit code 3, got: %d", res.ExitCode)
+	}
+	if res.Signal != nil {
+		t.Errorf("want no signal, got: %v", res.Signal)
+	}
+	if res.Duration() <= 0 {
+		t.Errorf("want positive duration, got: %v", res.Duration())
+	}
+	assert.Equal("456\n", string(res.Stdout))
+	assert.Equal("abc\n", string(res.Stderr))
+	assert.Equal("result [OUT] 123456\n", outB.String())
+	assert.Equal("result [ERR] abc\n", errB.String())
+}
+
+func TestExecutorEvents(t *testing.T) {
+	assert := assertions{t: t}
+	var events bytes.Buffer
+	cfg := config(t, func(cfg *shell.ExecutorConfig) {
+		cfg.Out = &bytes.Buffer{}
+		cfg.Err = &bytes.Buffer{}
+		cfg.Events = shell.NewJSONLinesSink(&events)
+	})
+	script := shell.Script{Label: "events", ScriptPath: "bash"}
+	_, err := shell.NewExecutor(cfg).RunScriptContext(context.Background(),
+		script, "-c", "echo first; printf last; exit 2")
+	if !errors.Is(err, shell.ErrNonZeroExit) {
+		t.Errorf("want %v, got: %v", shell.ErrNonZeroExit, err)
+	}
+	timestamp := regexp.MustCompile(`"time":"[^"]+"`)
+	got := timestamp.ReplaceAllString(events.String(), `"time":"T"`)
+	assert.Equal(`{"time":"T","type":"start","label":"events"}
+{"time":"T","type":"line","label":"events","stream":"out","line":"first"}
+{"time":"T","type":"line","label":"events","stream":"out","line":"last"}
+{"time":"T","type":"exit","label":"events","exitCode":2}
+`, got)
+}
+
+func TestExecutorArgsRoundTrip(t *testing.T) {
+	cfg := config(t, func(cfg *shell.ExecutorConfig) {
+		cfg.Out = io.Discard
+		cfg.Err = io.Discard
+		cfg.CaptureLimit = 1 << 16
+	})
+	exec := shell.NewExecutor(cfg)
+	printf := shell.Script{Label: "printf", ScriptPath: "printf"}
+	roundTrip := func(arg string) bool {
+		res, err := exec.RunScriptContext(context.Background(), printf, "%s", arg)
+		if err != nil {
+			t.Errorf("%q: %v", arg, err)
+			return false
+		}
+		if string(res.Stdout) != arg {
+			t.Errorf("want: %q\n got: %q", arg, res.Stdout)
+			return false
+		}
+		return true
+	}
+	for _, arg := range []string{
+		"", " ", "$HOME", "${HOME}", "`id`", "$(id)", "\\", "\\\n",
+		"\n", "'", "''", `"`, `\"`, "!", "*", "a b\tc", "-n", "\xff\xfe",
+	} {
+		roundTrip(arg)
+	}
+	err := quick.Check(func(b []byte) bool {
+		return roundTrip(strings.ReplaceAll(string(b), "\x00", ""))
+	}, &quick.Config{MaxCount: 50})
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = exec.RunScriptContext(context.Background(), printf, "a\x00b")
+	if !errors.Is(err, shell.ErrInvalidArgument) {
+		t.Errorf("want %v, got: %v", shell.ErrInvalidArgument, err)
+	}
+}
+
+func TestExecutorQuotedPaths(t *testing.T) {
+	assert := assertions{t: t}
+	root := path.Join(t.TempDir(), "it's a $HOME `dir`")
+	assert.NoError(os.MkdirAll(root, 0o755))
+	script := "print 'cwd'.sh"
+	assert.NoError(os.WriteFile(path.Join(root, script),
+		[]byte("#!/usr/bin/env bash\npwd\n"), 0o755))
+	loc, err := shell.NewFixedProjectLocation(root)
+	assert.NoError(err)
+	var outB bytes.Buffer
+	exec := shell.NewExecutor(shell.ExecutorConfig{
+		ProjectLocation: loc,
+		Streams:         shell.Streams{Out: &outB, Err: &outB},
+		Labels:          shell.Labels{SkipDate: true},
+	})
+	assert.NoError(exec.RunScript(shell.Script{Label: "cwd", ScriptPath: "./" + script}))
+	assert.Equal("cwd [OUT] "+root+"\n", outB.String())
+}
+
+func TestExecutorOverlays(t *testing.T) {
+	assert := assertions{t: t}
+	var outB bytes.Buffer
+	cfg := config(t, func(cfg *shell.ExecutorConfig) {
+		cfg.Out = &outB
+		cfg.Err = &outB
+		cfg.SkipDate = true
+		cfg.Environ = append(os.Environ(), "GOPROXY=https://proxy.golang.org", "GOFLAGS=-mod=mod")
+	})
+	exec := shell.NewExecutor(cfg)
+	script := shell.Script{
+		Label:      "overlay",
+		ScriptPath: "shell/fail-example.sh",
+		Environ:    []string{"GOPROXY=direct"},
+		Unsetenv:   []string{"GOFLAGS"},
+		Dir:        "pkg",
+		Stdin:      "from stdin\n",
+	}
+	assert.NoError(exec.RunScript(script, "expected err"))
+	assert.Equal("overlay [ERR] expected err\n", outB.String())
+
+	outB.Reset()
+	script.ScriptPath = "bash"
+	assert.NoError(exec.RunScript(script, "-c",
+		`echo "$(basename "$PWD") ${GOPROXY} ${GOFLAGS:-unset} $(cat)"`))
+	assert.Equal("overlay [OUT] pkg direct unset from stdin\n", outB.String())
+
+	outB.Reset()
+	assert.NoError(exec.RunFunction(shell.Function{
+		Script: shell.Script{
+			Label:      "pwd",
+			ScriptPath: "shell/testdata/session-example.sh",
+			Dir:        "shell",
+		},
+		FunctionName: "pwd",
+	}))
+	assert.Contains(outB.String(), "/shell\n")
+}
+
+func TestExecutorTrace(t *testing.T) {
+	assert := assertions{t: t}
+	var outB, errB, traceB bytes.Buffer
+	cfg := config(t, func(cfg *shell.ExecutorConfig) {
+		cfg.Out = &outB
+		cfg.Err = &errB
+		cfg.SkipDate = true
+		cfg.TraceSink = shell.NewTraceWriterSink(&traceB)
+	})
+	exec := shell.NewExecutor(cfg)
+	failing := shell.Function{
+		Script: shell.Script{
+			Label:      "fail",
+			ScriptPath: "shell/testdata/session-example.sh",
+		},
+		FunctionName: "fail",
+	}
+	if err := exec.RunFunction(failing, "boom"); err == nil {
+		t.Fatal("expected error")
+	}
+	assert.Contains(traceB.String(), "--- trace of fail (session-example.sh), exit code 3\n")
+	assert.Contains(traceB.String(), "session-example.sh:32:fail: echo boom")
+	assert.Equal("fail [ERR] boom\n", errB.String())
+
+	greet := failing
+	greet.Label = "greet"
+	greet.FunctionName = "greet"
+
+	traceB.Reset()
+	assert.NoError(exec.RunFunction(greet, "ok"))
+	assert.Equal("", traceB.String())
+
+	cfg.TraceAlways = true
+	assert.NoError(shell.NewExecutor(cfg).RunFunction(greet, "ok"))
+	assert.Contains(traceB.String(), "--- trace of greet (session-example.sh), exit code 0\n")
+
+	blocker := path.Join(t.TempDir(), "file")
+	assert.NoError(os.WriteFile(blocker, nil, 0o600))
+	cfg.TraceSink = shell.NewTraceDirSink(path.Join(blocker, "traces"))
+	err := shell.NewExecutor(cfg).RunFunction(greet, "ok")
+	if err == nil || !strings.Contains(err.Error(), "can't store the trace of greet") {
+		t.Errorf("want the trace error, got: %v", err)
+	}
+}
+
+func TestExecutorTraceScript(t *testing.T) {
+	assert := assertions{t: t}
+	var outB, traceB bytes.Buffer
+	cfg := config(t, func(cfg *shell.ExecutorConfig) {
+		cfg.Out = &outB
+		cfg.Err = &outB
+		cfg.SkipDate = true
+		cfg.GracePeriod = time.Minute
+		cfg.TraceSink = shell.NewTraceWriterSink(&traceB)
+	})
+	script := shell.Script{Label: "bg", ScriptPath: "bash"}
+	start := time.Now()
+	err := shell.NewExecutor(cfg).RunScript(script, "-c",
+		`echo traced; sleep 5 >/dev/null 2>&1 & exit 4`)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("the background process held the run for %v", elapsed)
+	}
+	assert.Contains(traceB.String(), "--- trace of bg (bash), exit code 4\n")
+	// bash run as root doesn't take PS4 from the environment, so the
+	// prefix of the lines of the script itself may differ
+	assert.Contains(traceB.String(), "echo traced\n")
+	assert.Equal("bg [OUT] traced\n", outB.String())
+}
+
+func TestExecutorLineBuffered(t *testing.T) {
+	assert := assertions{t: t}
+	var outB bytes.Buffer
+	cfg := config(t, func(cfg *shell.ExecutorConfig) {
+		cfg.Out = &outB
+		cfg.Err = &outB
+		cfg.SkipDate = true
+		cfg.LineBuffered = true
+	})
+	exec := shell.NewExecutor(cfg)
+	assert.NoError(exec.RunFunction(shell.Function{
+		Script: shell.Script{
+			Label:      "greet",
+			ScriptPath: "shell/testdata/session-example.sh",
+		},
+		FunctionName: "greet",
+	}, "World"))
+	assert.Equal("greet [OUT] sourced\n"+
+		"greet [OUT] Hello, World!\n"+
+		"greet [OUT] no newline\n", outB.String())
+}
+
+func TestExecutorColor(t *testing.T) {
+	t.Setenv(shell.NoColorEnvVar, "")
+	t.Setenv(shell.ForceColorEnvVar, "")
+	const colored = "\x1b[1;31mred\x1b[0m \x1b]8;;https://knative.dev\x07link\x1b]8;;\x07\n"
+	script := shell.Script{Label: "color", ScriptPath: "bash"}
+	args := []string{"-c", `printf "$1" && echo "force=${CLICOLOR_FORCE:-}"`, "--", colored}
+	tests := []struct {
+		name  string
+		mode  shell.ColorMode
+		force string
+		want  string
+	}{{
+		name: "auto, not a terminal",
+		mode: shell.ColorAuto,
+		want: "color [OUT] red link\ncolor [OUT] force=\n",
+	}, {
+		name:  "auto, forced",
+		mode:  shell.ColorAuto,
+		force: "1",
+		want: "\x1b[32mcolor [OUT] \x1b[0m" + colored +
+			"\x1b[32mcolor [OUT] \x1b[0mforce=1\n",
+	}, {
+		name: "keep",
+		mode: shell.ColorKeep,
+		want: "color [OUT] " + colored + "color [OUT] force=\n",
+	}, {
+		name: "never",
+		mode: shell.ColorNever,
+		want: "color [OUT] red link\ncolor [OUT] force=\n",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assertions{t: t}
+			t.Setenv(shell.ForceColorEnvVar, tt.force)
+			var outB bytes.Buffer
+			cfg := config(t, func(cfg *shell.ExecutorConfig) {
+				cfg.Out = &outB
+				cfg.SkipDate = true
+				cfg.Color = tt.mode
+				cfg.Environ = []string{"PATH=" + os.Getenv("PATH")}
+			})
+			assert.NoError(shell.NewExecutor(cfg).RunScript(script, args...))
+			assert.Equal(tt.want, outB.String())
+		})
+	}
+}
+
+func TestTraceDirSink(t *testing.T) {
+	assert := assertions{t: t}
+	dir := t.TempDir()
+	sink := shell.NewTraceDirSink(dir)
+	assert.NoError(sink.Trace(shell.Script{Label: "a/b c"}, shell.Result{}, []byte("+ true\n")))
+	files, err := os.ReadDir(dir)
+	assert.NoError(err)
+	if len(files) != 1 || !strings.HasPrefix(files[0].Name(), "a_b_c-") {
+		t.Fatalf("unexpected trace files: %v", files)
+	}
+	bytes, err := os.ReadFile(path.Join(dir, files[0].Name()))
+	assert.NoError(err)
+	assert.Equal("+ true\n", string(bytes))
+
+	// a file stands in the way of the directory
+	file := path.Join(dir, files[0].Name())
+	sink = shell.NewTraceDirSink(path.Join(file, "traces"))
+	if err = sink.Trace(shell.Script{Label: "a"}, shell.Result{}, nil); err == nil {
+		t.Error("want an error for an unwritable trace directory")
+	}
+}
+
 func helloWorldTestCase(t *testing.T) testcase {
 	return testcase{
 		"echo Hello, World!",
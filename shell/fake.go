@@ -0,0 +1,267 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnexpectedCall is returned by the FakeExecutor if none of its responses
+// match the call.
+var ErrUnexpectedCall = errors.New("unexpected call")
+
+// Call represents a single invocation of a script or function.
+type Call struct {
+	Script
+	FunctionName string   `json:"functionName,omitempty"`
+	Args         []string `json:"args,omitempty"`
+}
+
+// ScriptCall creates a Call of the script with args.
+func ScriptCall(script Script, args ...string) Call {
+	return Call{
+		Script: script,
+		Args:   args,
+	}
+}
+
+// FunctionCall creates a Call of the function with args.
+func FunctionCall(fn Function, args ...string) Call {
+	c := ScriptCall(fn.Script, args...)
+	c.FunctionName = fn.FunctionName
+	return c
+}
+
+func (c Call) String() string {
+	name := c.ScriptPath
+	if c.FunctionName != "" {
+		name += " " + c.FunctionName
+	}
+	return fmt.Sprintf("%s (%s) %q", c.Label, name, c.Args)
+}
+
+func (c Call) equal(o Call) bool {
+	return c.Label == o.Label &&
+		c.ScriptPath == o.ScriptPath &&
+		slices.Equal(c.Environ, o.Environ) &&
+		slices.Equal(c.Unsetenv, o.Unsetenv) &&
+		c.Dir == o.Dir &&
+		c.Stdin == o.Stdin &&
+		c.FunctionName == o.FunctionName &&
+		slices.Equal(c.Args, o.Args)
+}
+
+// Response is a scripted response of the FakeExecutor. The empty matching
+// fields match any call.
+type Response struct {
+	// Label matches the label of the called script or function.
+	Label string
+	// ScriptPath matches the path of the called script.
+	ScriptPath string
+	// FunctionName matches the name of the called function.
+	FunctionName string
+	// Args matches the exact args of the call, if not nil.
+	Args []string
+	// ArgsPattern matches the args of the call joined with spaces.
+	ArgsPattern *regexp.Regexp
+	// Times limits how many times the response can be used, zero means
+	// unlimited.
+	Times int
+
+	// Stdout is written to the output stream of the executor.
+	Stdout string
+	// Stderr is written to the error stream of the executor.
+	Stderr string
+	// ExitCode is the simulated exit code of the call.
+	ExitCode int
+	// Cause is the cause of the ExitError, ErrNonZeroExit if nil. Use
+	// ErrTimedOut or ErrCanceled to simulate an interrupted call.
+	Cause error
+	// Err is returned as is, if set.
+	Err error
+}
+
+func (r Response) matches(c Call) bool {
+	return (r.Label == "" || r.Label == c.Label) &&
+		(r.ScriptPath == "" || r.ScriptPath == c.ScriptPath) &&
+		(r.FunctionName == "" || r.FunctionName == c.FunctionName) &&
+		(r.Args == nil || slices.Equal(r.Args, c.Args)) &&
+		(r.ArgsPattern == nil || r.ArgsPattern.MatchString(strings.Join(c.Args, " ")))
+}
+
+// NewFakeExecutor creates an in-memory Executor, that records the calls and
+// returns the first of the given responses matching each call. The output of
+// the responses is written to the streams of the config.
+func NewFakeExecutor(config ExecutorConfig, responses ...Response) *FakeExecutor {
+	configureDefaultValues(&config)
+	return &FakeExecutor{
+		ExecutorConfig: config,
+		responses:      responses,
+		used:           make([]int, len(responses)),
+	}
+}
+
+// FakeExecutor is an Executor that doesn't run any processes. It is meant to
+// unit-test the code that uses the Executor.
+type FakeExecutor struct {
+	ExecutorConfig
+	mu        sync.Mutex
+	responses []Response
+	used      []int
+	calls     []Call
+}
+
+// RunScript records the call of a shell script, and returns a response.
+func (f *FakeExecutor) RunScript(script Script, args ...string) error {
+	_, err := f.RunScriptContext(context.Background(), script, args...)
+	return err
+}
+
+// RunFunction records the call of a shell function, and returns a response.
+func (f *FakeExecutor) RunFunction(fn Function, args ...string) error {
+	_, err := f.RunFunctionContext(context.Background(), fn, args...)
+	return err
+}
+
+// RunScriptContext records the call of a shell script, and returns a response.
+func (f *FakeExecutor) RunScriptContext(ctx context.Context, script Script, args ...string) (Result, error) {
+	return f.call(ctx, ScriptCall(script, args...))
+}
+
+// RunFunctionContext records the call of a shell function, and returns
+// a response.
+func (f *FakeExecutor) RunFunctionContext(ctx context.Context, fn Function, args ...string) (Result, error) {
+	return f.call(ctx, FunctionCall(fn, args...))
+}
+
+// Calls returns the recorded calls in order.
+func (f *FakeExecutor) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+// AssertCalled asserts that the call was made at least once.
+func (f *FakeExecutor) AssertCalled(t TestingT, want Call) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	calls := f.Calls()
+	for _, c := range calls {
+		if c.equal(want) {
+			return true
+		}
+	}
+	t.Errorf("expected call: %s\nrecorded calls:\n%s", want, formatCalls(calls))
+	return false
+}
+
+// AssertCalls asserts that exactly the given calls were made, in order.
+func (f *FakeExecutor) AssertCalls(t TestingT, want ...Call) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	calls := f.Calls()
+	if !slices.EqualFunc(calls, want, Call.equal) {
+		t.Errorf("expected calls:\n%s\nrecorded calls:\n%s",
+			formatCalls(want), formatCalls(calls))
+		return false
+	}
+	return true
+}
+
+func (f *FakeExecutor) call(ctx context.Context, c Call) (Result, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, c)
+	// the calls with a done context don't use up the responses
+	resp, ok := Response{}, true
+	if ctx.Err() == nil {
+		resp, ok = f.respond(c)
+	}
+	f.mu.Unlock()
+	if !ok {
+		return Result{}, fmt.Errorf("%w: %s", ErrUnexpectedCall, c)
+	}
+	outs := newOutputs(f.ExecutorConfig, c.Label)
+	res := Result{StartTime: time.Now()}
+	outs.events.start(res.StartTime)
+	// the process of a done context wouldn't be started
+	if err := ctx.Err(); err != nil {
+		outs.events.fail(time.Now(), err)
+		return Result{}, err
+	}
+	if resp.Err != nil {
+		outs.events.fail(time.Now(), resp.Err)
+		return Result{}, resp.Err
+	}
+	_, _ = outs.stdout.Write([]byte(resp.Stdout))
+	_, _ = outs.stderr.Write([]byte(resp.Stderr))
+	res.EndTime = time.Now()
+	res.ExitCode = resp.ExitCode
+	outs.finish(&res)
+	if resp.Cause != nil || res.ExitCode != 0 {
+		cause := resp.Cause
+		if cause == nil {
+			cause = ErrNonZeroExit
+		}
+		return res, &ExitError{
+			Label:      c.Label,
+			ScriptPath: c.ScriptPath,
+			Result:     res,
+			Cause:      cause,
+			Err:        fmt.Errorf("exit status %d", res.ExitCode),
+		}
+	}
+	return res, nil
+}
+
+func (f *FakeExecutor) respond(c Call) (Response, bool) {
+	for i, r := range f.responses {
+		if r.Times > 0 && f.used[i] >= r.Times {
+			continue
+		}
+		if r.matches(c) {
+			f.used[i]++
+			return r, true
+		}
+	}
+	return Response{}, false
+}
+
+func formatCalls(calls []Call) string {
+	lines := make([]string, len(calls))
+	for i, c := range calls {
+		lines[i] = fmt.Sprintf("  %d. %s", i+1, c)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TestingT is an interface wrapper around *testing.T.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+type tHelper interface {
+	Helper()
+}
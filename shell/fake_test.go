@@ -0,0 +1,172 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"testing"
+	"time"
+
+	"knative.dev/hack/shell"
+)
+
+func TestFakeExecutor(t *testing.T) {
+	assert := assertions{t: t}
+	var outB, errB bytes.Buffer
+	exec := shell.NewFakeExecutor(shell.ExecutorConfig{
+		Streams: shell.Streams{Out: &outB, Err: &errB},
+		Labels:  shell.Labels{SkipDate: true},
+	}, shell.Response{
+		FunctionName: "wait_until_pods_running",
+		ArgsPattern:  regexp.MustCompile(`^knative-`),
+		Stdout:       "Waiting until all pods are running\n",
+	}, shell.Response{
+		FunctionName: "abort",
+		Stderr:       "ERROR: reason\n",
+		ExitCode:     42,
+	})
+
+	assert.NoError(exec.RunFunction(fn("wait_until_pods_running"), "knative-serving"))
+	err := exec.RunFunction(fn("abort"), "reason")
+	var exitErr *shell.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 42 {
+		t.Errorf("want exit code 42, got: %v", err)
+	}
+	err = exec.RunFunction(fn("wait_until_pods_running"), "default")
+	if !errors.Is(err, shell.ErrUnexpectedCall) {
+		t.Errorf("want %v, got: %v", shell.ErrUnexpectedCall, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = exec.RunFunctionContext(ctx, fn("abort"), "canceled")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v, got: %v", context.Canceled, err)
+	}
+
+	assert.Equal("wait_until_pods_running [OUT] Waiting until all pods are running\n", outB.String())
+	assert.Equal("abort [ERR] ERROR: reason\n", errB.String())
+	exec.AssertCalled(t, shell.FunctionCall(fn("abort"), "reason"))
+	exec.AssertCalls(t,
+		shell.FunctionCall(fn("wait_until_pods_running"), "knative-serving"),
+		shell.FunctionCall(fn("abort"), "reason"),
+		shell.FunctionCall(fn("wait_until_pods_running"), "default"),
+		shell.FunctionCall(fn("abort"), "canceled"),
+	)
+
+	rec := &recordingT{}
+	exec.AssertCalled(rec, shell.FunctionCall(fn("abort")))
+	exec.AssertCalls(rec, shell.FunctionCall(fn("abort"), "reason"))
+	if len(rec.errors) != 2 {
+		t.Errorf("want 2 assertion failures, got: %q", rec.errors)
+	}
+}
+
+func TestFakeExecutorEvents(t *testing.T) {
+	assert := assertions{t: t}
+	var events bytes.Buffer
+	errStart := errors.New("can't start")
+	exec := shell.NewFakeExecutor(shell.ExecutorConfig{
+		Streams: shell.Streams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}},
+		Events:  shell.NewJSONLinesSink(&events),
+	}, shell.Response{
+		FunctionName: "greet",
+		Stdout:       "hi\n",
+	}, shell.Response{
+		FunctionName: "broken",
+		Err:          errStart,
+	})
+
+	assert.NoError(exec.RunFunction(fn("greet")))
+	if err := exec.RunFunction(fn("broken")); !errors.Is(err, errStart) {
+		t.Errorf("want %v, got: %v", errStart, err)
+	}
+	types := regexp.MustCompile(`"type":"(\w+)"`).FindAllStringSubmatch(events.String(), -1)
+	got := ""
+	for _, m := range types {
+		got += m[1] + " "
+	}
+	assert.Equal("start line exit start error ", got)
+	assert.Contains(events.String(), `"error":"can't start"`)
+}
+
+func TestRecordingExecutor(t *testing.T) {
+	assert := assertions{t: t}
+	cfg := config(t, func(cfg *shell.ExecutorConfig) {
+		cfg.Out = &bytes.Buffer{}
+		cfg.Err = &bytes.Buffer{}
+		cfg.CaptureLimit = 1024
+	})
+	script := shell.Script{Label: "bash", ScriptPath: "bash"}
+	rec := shell.NewRecordingExecutor(shell.NewExecutor(cfg))
+	assert.NoError(rec.RunScript(script, "-c", "echo recorded"))
+	err := rec.RunScript(script, "-c", "echo failed >&2; exit 7")
+	if !errors.Is(err, shell.ErrNonZeroExit) {
+		t.Errorf("want %v, got: %v", shell.ErrNonZeroExit, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err = rec.RunScriptContext(ctx, script, "-c", "sleep 5"); !errors.Is(err, shell.ErrTimedOut) {
+		t.Errorf("want %v, got: %v", shell.ErrTimedOut, err)
+	}
+	file := path.Join(t.TempDir(), "interactions.json")
+	assert.NoError(rec.Save(file))
+
+	interactions, err := shell.LoadInteractions(file)
+	assert.NoError(err)
+	var outB, errB bytes.Buffer
+	replay := shell.NewFakeExecutor(shell.ExecutorConfig{
+		Streams: shell.Streams{Out: &outB, Err: &errB},
+		Labels:  shell.Labels{SkipDate: true},
+	}, shell.ReplayResponses(interactions)...)
+	other := shell.Script{Label: "bash", ScriptPath: "/bin/bash"}
+	if _, err = replay.RunScriptContext(context.Background(), other, "-c", "echo recorded"); !errors.Is(err, shell.ErrUnexpectedCall) {
+		t.Errorf("want %v, got: %v", shell.ErrUnexpectedCall, err)
+	}
+	if _, err = replay.RunScriptContext(context.Background(), script, "-c", "sleep 5"); !errors.Is(err, shell.ErrTimedOut) {
+		t.Errorf("want %v, got: %v", shell.ErrTimedOut, err)
+	}
+	res, err := replay.RunScriptContext(context.Background(), script, "-c", "echo recorded")
+	assert.NoError(err)
+	if res.ExitCode != 0 {
+		t.Errorf("want exit code 0, got: %d", res.ExitCode)
+	}
+	res, err = replay.RunScriptContext(context.Background(), script, "-c", "echo failed >&2; exit 7")
+	if !errors.Is(err, shell.ErrNonZeroExit) || res.ExitCode != 7 {
+		t.Errorf("want exit code 7, got: %v", err)
+	}
+	assert.Equal("bash [OUT] recorded\n", outB.String())
+	assert.Equal("bash [ERR] failed\n", errB.String())
+	replay.AssertCalls(t,
+		shell.ScriptCall(other, "-c", "echo recorded"),
+		shell.ScriptCall(script, "-c", "sleep 5"),
+		shell.ScriptCall(script, "-c", "echo recorded"),
+		shell.ScriptCall(script, "-c", "echo failed >&2; exit 7"),
+	)
+}
+
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
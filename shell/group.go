@@ -0,0 +1,130 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// ErrSkipped is returned for the jobs of the Group that weren't started,
+// because the group was canceled before.
+var ErrSkipped = errors.New("job skipped")
+
+// Group runs many scripts and functions in parallel, with a bounded number of
+// workers. The output of each job is prefixed with its own label, and written
+// a whole line at a time, so lines of different jobs don't interleave. The
+// line buffering options of the ExecutorConfig are honored.
+type Group struct {
+	ExecutorConfig
+	// Concurrency is the maximum number of jobs running at once. Zero means
+	// the number of CPUs.
+	Concurrency int
+	// FailFast cancels the running jobs, and skips the remaining ones, after
+	// the first failure.
+	FailFast bool
+}
+
+// JobResult holds the outcome of a single job of the Group.
+type JobResult struct {
+	Call
+	Result
+	Err error
+}
+
+// Run executes the calls, and returns their results in the same order. In
+// the fail-fast mode, the first failure is returned, otherwise all of the
+// failures are joined.
+func (g Group) Run(ctx context.Context, calls ...Call) ([]JobResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cfg := g.ExecutorConfig
+	configureDefaultValues(&cfg)
+	cfg.LineBuffered = true
+	out := NewLineMux(cfg.Out, cfg.LineOptions)
+	errw := out
+	if !sameWriter(cfg.Err, cfg.Out) {
+		errw = NewLineMux(cfg.Err, cfg.LineOptions)
+	}
+	cfg.Out, cfg.Err = out, errw
+	workers := g.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+	)
+	results := make([]JobResult, len(calls))
+	jobs := make(chan int)
+	for w := 0; w < min(workers, len(calls)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					results[i] = JobResult{
+						Call: calls[i],
+						Err:  fmt.Errorf("%w: %s: %w", ErrSkipped, calls[i].Label, err),
+					}
+					continue
+				}
+				results[i] = runJob(ctx, cfg, calls[i])
+				if err := results[i].Err; err != nil && g.FailFast {
+					failOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+	for i := range calls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return results, firstErr
+	}
+	errs := make([]error, 0, len(results))
+	for _, r := range results {
+		errs = append(errs, r.Err)
+	}
+	return results, errors.Join(errs...)
+}
+
+func runJob(ctx context.Context, cfg ExecutorConfig, c Call) JobResult {
+	exec := NewExecutor(cfg)
+	var (
+		res Result
+		err error
+	)
+	if c.FunctionName != "" {
+		fn := Function{Script: c.Script, FunctionName: c.FunctionName}
+		res, err = exec.RunFunctionContext(ctx, fn, c.Args...)
+	} else {
+		res, err = exec.RunScriptContext(ctx, c.Script, c.Args...)
+	}
+	return JobResult{Call: c, Result: res, Err: err}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"knative.dev/hack/shell"
+)
+
+func TestGroup(t *testing.T) {
+	assert := assertions{t: t}
+	var outB bytes.Buffer
+	cfg := config(t, func(cfg *shell.ExecutorConfig) {
+		cfg.Out = &outB
+		cfg.Err = &outB
+		cfg.SkipDate = true
+	})
+	calls := make([]shell.Call, 0, 4)
+	for i := 0; i < 4; i++ {
+		calls = append(calls, shell.ScriptCall(
+			shell.Script{Label: fmt.Sprintf("job-%d", i), ScriptPath: "bash"},
+			"-c", "printf 'partial '; sleep 0.01; echo line 1; printf 'partial '; echo line 2; "+
+				"printf 'partial '; sleep 0.01; echo line 3; printf end"))
+	}
+	results, err := shell.Group{ExecutorConfig: cfg, Concurrency: 2}.
+		Run(context.Background(), calls...)
+	assert.NoError(err)
+	if len(results) != len(calls) {
+		t.Fatalf("want %d results, got: %d", len(calls), len(results))
+	}
+	lines := strings.Split(strings.TrimSuffix(outB.String(), "\n"), "\n")
+	for i, r := range results {
+		assert.NoError(r.Err)
+		assert.Equal(calls[i].Label, r.Label)
+		for j := 1; j <= 3; j++ {
+			assert.Contains(outB.String(),
+				fmt.Sprintf("%s [OUT] partial line %d\n", r.Label, j))
+		}
+		// the unterminated last line is terminated, to not join other lines
+		assert.Contains(outB.String(), r.Label+" [OUT] end\n")
+	}
+	if len(lines) != 4*4 {
+		t.Errorf("want %d lines, got:\n%s", 4*4, outB.String())
+	}
+}
+
+func TestGroupFailures(t *testing.T) {
+	failing := shell.ScriptCall(
+		shell.Script{Label: "failing", ScriptPath: "bash"}, "-c", "exit 3")
+	sleeping := shell.ScriptCall(
+		shell.Script{Label: "sleeping", ScriptPath: "sleep"}, "0.5")
+	tests := []struct {
+		name     string
+		failFast bool
+		want     []error
+	}{{
+		name:     "fail fast",
+		failFast: true,
+		want:     []error{shell.ErrNonZeroExit, shell.ErrCanceled, shell.ErrSkipped},
+	}, {
+		name: "collect all",
+		want: []error{shell.ErrNonZeroExit, nil, shell.ErrNonZeroExit},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config(t, func(cfg *shell.ExecutorConfig) {
+				cfg.Out = &bytes.Buffer{}
+				cfg.Err = &bytes.Buffer{}
+				cfg.GracePeriod = 100 * time.Millisecond
+			})
+			g := shell.Group{ExecutorConfig: cfg, Concurrency: 2, FailFast: tt.failFast}
+			results, err := g.Run(context.Background(), failing, sleeping, failing)
+			if !errors.Is(err, shell.ErrNonZeroExit) {
+				t.Errorf("want %v, got: %v", shell.ErrNonZeroExit, err)
+			}
+			for i, want := range tt.want {
+				got := results[i].Err
+				if (want == nil && got != nil) || !errors.Is(got, want) {
+					t.Errorf("job #%d: want %v, got: %v", i, want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// AllowedCallersEnvVar is the name of the environment variable that holds
+	// a comma separated list of additional regexp patterns of the functions
+	// allowed to use this package.
+	AllowedCallersEnvVar = "KNATIVE_HACK_SHELL_ALLOWED_CALLERS"
+)
+
+// defaultAllowedCallers are the patterns of the functions that are allowed to
+// use this package by default.
+var defaultAllowedCallers = []string{
+	"knative.+/test/upgrade",
+	"knative(:?\\.dev/|-)hack/shell",
+}
+
+// compiledPatterns caches the compiled regexp patterns.
+var compiledPatterns sync.Map //nolint:gochecknoglobals
+
+// CallerPolicy decides whether this package can be used from the function
+// given by its full name, like: knative.dev/serving/test/upgrade.TestUpgrades.
+type CallerPolicy interface {
+	// Check returns an error wrapping ErrCallerNotAllowed if the function isn't
+	// allowed to use this package.
+	Check(funcName string) error
+}
+
+// CallerPolicyFunc is an adapter to use ordinary functions as CallerPolicy.
+type CallerPolicyFunc func(funcName string) error
+
+// Check calls f(funcName).
+func (f CallerPolicyFunc) Check(funcName string) error {
+	return f(funcName)
+}
+
+// NewCallerPolicy creates a CallerPolicy that allows the functions matching
+// any of the given regexp patterns.
+func NewCallerPolicy(patterns ...string) (CallerPolicy, error) {
+	res := make(patternPolicy, 0, len(patterns))
+	for _, pattern := range patterns {
+		r, err := compilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// DefaultCallerPolicy returns the CallerPolicy that allows the Knative upgrade
+// tests, this package itself, and the functions matching patterns given in
+// the KNATIVE_HACK_SHELL_ALLOWED_CALLERS environment variable. The variable is
+// read when the policy is created.
+func DefaultCallerPolicy() CallerPolicy {
+	patterns := slices.Clone(defaultAllowedCallers)
+	for _, pattern := range strings.Split(os.Getenv(AllowedCallersEnvVar), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	policy, err := NewCallerPolicy(patterns...)
+	if err != nil {
+		err = fmt.Errorf("%w: invalid %s: %w",
+			ErrCallerNotAllowed, AllowedCallersEnvVar, err)
+		return CallerPolicyFunc(func(string) error {
+			return err
+		})
+	}
+	return policy
+}
+
+// LocationOption configures the creation of a ProjectLocation.
+type LocationOption func(*locationOptions)
+
+type locationOptions struct {
+	policy  CallerPolicy
+	allowed []CallerPolicy
+	err     error
+}
+
+// WithCallerPolicy replaces the DefaultCallerPolicy with the given one.
+func WithCallerPolicy(policy CallerPolicy) LocationOption {
+	return func(o *locationOptions) {
+		o.policy = policy
+	}
+}
+
+// WithAllowedCallers extends the caller policy with additional regexp
+// patterns of the allowed functions. The invalid patterns fail the creation
+// of the ProjectLocation.
+func WithAllowedCallers(patterns ...string) LocationOption {
+	extra, err := NewCallerPolicy(patterns...)
+	return func(o *locationOptions) {
+		if err != nil {
+			o.err = errors.Join(o.err, fmt.Errorf("invalid allowed callers: %w", err))
+			return
+		}
+		o.allowed = append(o.allowed, extra)
+	}
+}
+
+func callerPolicy(opts []LocationOption) (CallerPolicy, error) {
+	var o locationOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.policy == nil {
+		o.policy = DefaultCallerPolicy()
+	}
+	if o.err != nil || len(o.allowed) == 0 {
+		return o.policy, o.err
+	}
+	return CallerPolicyFunc(func(funcName string) error {
+		for _, extra := range o.allowed {
+			if extra.Check(funcName) == nil {
+				return nil
+			}
+		}
+		return o.policy.Check(funcName)
+	}), nil
+}
+
+type patternPolicy []*regexp.Regexp
+
+func (p patternPolicy) Check(funcName string) error {
+	for _, r := range p {
+		if r.MatchString(funcName) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w, tried using from: %s",
+		ErrCallerNotAllowed, funcName)
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if r, ok := compiledPatterns.Load(pattern); ok {
+		return r.(*regexp.Regexp), nil
+	}
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(pattern, r)
+	return r, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell_test
+
+import (
+	"errors"
+	"testing"
+
+	"knative.dev/hack/shell"
+)
+
+func TestDefaultCallerPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		funcName string
+		env      string
+		allowed  bool
+	}{{
+		name:     "upgrade test",
+		funcName: "knative.dev/serving/test/upgrade.TestServingUpgrades",
+		allowed:  true,
+	}, {
+		name:     "shell package",
+		funcName: "knative.dev/hack/shell_test.TestNewExecutor",
+		allowed:  true,
+	}, {
+		name:     "e2e helpers",
+		funcName: "knative.dev/eventing/test/e2e/helpers.Install",
+	}, {
+		name:     "e2e helpers allowed by env",
+		funcName: "knative.dev/eventing/test/e2e/helpers.Install",
+		env:      "example.com/foo, knative.dev/eventing/test/e2e",
+		allowed:  true,
+	}, {
+		name:     "invalid env pattern",
+		funcName: "knative.dev/serving/test/upgrade.TestServingUpgrades",
+		env:      "knative.dev/(",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(shell.AllowedCallersEnvVar, tt.env)
+			err := shell.DefaultCallerPolicy().Check(tt.funcName)
+			if tt.allowed && err != nil {
+				t.Errorf("want allowed, got: %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, shell.ErrCallerNotAllowed) {
+				t.Errorf("want %v, got: %v", shell.ErrCallerNotAllowed, err)
+			}
+		})
+	}
+}
+
+func TestNewCallerPolicy(t *testing.T) {
+	assert := assertions{t: t}
+	policy, err := shell.NewCallerPolicy("^example.com/e2e\\.")
+	assert.NoError(err)
+	assert.NoError(policy.Check("example.com/e2e.TestFoo"))
+	err = policy.Check("example.com/e2e/sub.TestFoo")
+	if !errors.Is(err, shell.ErrCallerNotAllowed) {
+		t.Errorf("want %v, got: %v", shell.ErrCallerNotAllowed, err)
+	}
+	assert.Contains(err.Error(), "example.com/e2e/sub.TestFoo")
+
+	_, err = shell.NewCallerPolicy("(")
+	if err == nil {
+		t.Error("want error for invalid pattern")
+	}
+}
+
+func TestWithAllowedCallers(t *testing.T) {
+	assert := assertions{t: t}
+	// the policy doesn't allow this test, unlike the default one
+	strict, err := shell.NewCallerPolicy("^example.com/")
+	assert.NoError(err)
+	_, err = shell.NewFixedProjectLocation("/", shell.WithCallerPolicy(strict))
+	if !errors.Is(err, shell.ErrCallerNotAllowed) {
+		t.Errorf("want %v, got: %v", shell.ErrCallerNotAllowed, err)
+	}
+	_, err = shell.NewFixedProjectLocation("/", shell.WithCallerPolicy(strict),
+		shell.WithAllowedCallers(`^knative\.dev/hack/shell_test\.TestWithAllowedCallers$`))
+	assert.NoError(err)
+
+	_, err = shell.NewFixedProjectLocation("/", shell.WithAllowedCallers("("))
+	if err == nil || errors.Is(err, shell.ErrCallerNotAllowed) {
+		t.Errorf("want an invalid pattern error, got: %v", err)
+	}
+	assert.Contains(err.Error(), "invalid allowed callers")
+}
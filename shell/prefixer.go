@@ -19,6 +19,9 @@ package shell
 import (
 	"bytes"
 	"io"
+	"os"
+	"sync"
+	"time"
 )
 
 // NewPrefixer creates a new prefixer that forwards all calls to Write() to
@@ -66,3 +69,157 @@ func (pf *prefixer) Write(payload []byte) (int, error) {
 	// return original length to satisfy io.Writer interface
 	return len(payload), nil
 }
+
+// LineOptions configures the line buffering of the LinePrefixer.
+type LineOptions struct {
+	// MaxLineLength splits the lines longer than that many bytes. Zero means
+	// no limit.
+	MaxLineLength int
+	// FlushTimeout is the time after which an unterminated line is written
+	// anyway. Zero means the line waits for the newline, Flush or Close.
+	FlushTimeout time.Duration
+}
+
+// LineMux serializes writes of many LinePrefixers into a single writer, so
+// the lines of different streams don't interleave.
+type LineMux struct {
+	mu      sync.Mutex
+	writer  io.Writer
+	options LineOptions
+}
+
+// NewLineMux creates a LineMux writing to the writer.
+func NewLineMux(writer io.Writer, options LineOptions) *LineMux {
+	return &LineMux{writer: writer, options: options}
+}
+
+// Write forwards the payload to the underlying writer, while holding the lock.
+func (m *LineMux) Write(payload []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.writer.Write(payload)
+}
+
+// Prefixer creates a new LinePrefixer, writing into this LineMux.
+func (m *LineMux) Prefixer(prefix func() string) *LinePrefixer {
+	return &LinePrefixer{mux: m, prefix: prefix}
+}
+
+// NewLinePrefixer creates a line buffered prefixer. Unlike NewPrefixer, it
+// is safe for concurrent use, and the prefix is computed only when the whole
+// line is available. Use a LineMux to write many streams into one writer.
+func NewLinePrefixer(writer io.Writer, prefix func() string, options LineOptions) *LinePrefixer {
+	return NewLineMux(writer, options).Prefixer(prefix)
+}
+
+// LinePrefixer buffers the written payload, and forwards it to the LineMux
+// a whole line at a time, with each line prefixed.
+type LinePrefixer struct {
+	mux    *LineMux
+	prefix func() string
+	mu     sync.Mutex
+	buf    []byte
+	timer  *time.Timer
+	gen    int
+	closed bool
+}
+
+func (lp *LinePrefixer) Write(payload []byte) (int, error) {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	if lp.closed {
+		return 0, os.ErrClosed
+	}
+	lp.buf = append(lp.buf, payload...)
+	err := lp.emit(false)
+	lp.armTimer()
+	return len(payload), err
+}
+
+// Flush writes the buffered unterminated line, followed by a newline.
+func (lp *LinePrefixer) Flush() error {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	err := lp.emit(true)
+	lp.armTimer()
+	return err
+}
+
+// Close flushes the buffered line. The writes after Close fail.
+func (lp *LinePrefixer) Close() error {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	if lp.closed {
+		return nil
+	}
+	err := lp.emit(true)
+	lp.armTimer()
+	lp.closed = true
+	return err
+}
+
+// emit writes the complete lines from the buffer, and with final set also the
+// unterminated one.
+func (lp *LinePrefixer) emit(final bool) error {
+	maxLen := lp.mux.options.MaxLineLength
+	var out []byte
+	rest := lp.buf
+	for len(rest) > 0 {
+		n := bytes.IndexByte(rest, '\n') + 1
+		if n == 0 || (maxLen > 0 && n > maxLen+1) {
+			switch {
+			case maxLen > 0 && len(rest) > maxLen:
+				n = maxLen
+			case final:
+				n = len(rest)
+			default:
+				lp.buf = append(lp.buf[:0], rest...)
+				return lp.write(out)
+			}
+			out = append(out, lp.prefix()...)
+			out = append(out, rest[:n]...)
+			out = append(out, '\n')
+		} else {
+			out = append(out, lp.prefix()...)
+			out = append(out, rest[:n]...)
+		}
+		rest = rest[n:]
+	}
+	lp.buf = lp.buf[:0]
+	return lp.write(out)
+}
+
+func (lp *LinePrefixer) write(out []byte) error {
+	if len(out) == 0 {
+		return nil
+	}
+	_, err := lp.mux.Write(out)
+	return err
+}
+
+// armTimer starts the flush timer, when an unterminated line starts to be
+// buffered, and stops it when the buffer is drained.
+func (lp *LinePrefixer) armTimer() {
+	timeout := lp.mux.options.FlushTimeout
+	if len(lp.buf) == 0 || timeout <= 0 {
+		if lp.timer != nil {
+			lp.timer.Stop()
+			lp.timer = nil
+		}
+		return
+	}
+	if lp.timer != nil {
+		return
+	}
+	lp.gen++
+	gen := lp.gen
+	lp.timer = time.AfterFunc(timeout, func() {
+		lp.mu.Lock()
+		defer lp.mu.Unlock()
+		if lp.gen != gen || lp.timer == nil {
+			return
+		}
+		lp.timer = nil
+		_ = lp.emit(true)
+	})
+}
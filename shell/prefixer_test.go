@@ -19,7 +19,10 @@ package shell_test
 import (
 	"bytes"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"knative.dev/hack/shell"
 )
@@ -68,3 +71,88 @@ test string 2
 		})
 	}
 }
+
+func TestLinePrefixer(t *testing.T) {
+	assert := assertions{t: t}
+	var buf bytes.Buffer
+	lineno := 0
+	lp := shell.NewLinePrefixer(&buf, func() string {
+		lineno++
+		return strconv.Itoa(lineno) + ") "
+	}, shell.LineOptions{MaxLineLength: 16})
+	for _, chunk := range []string{"Contin", "ue? ", "yes\n", "0123456789abcdef0123456789\nlast"} {
+		_, err := lp.Write([]byte(chunk))
+		assert.NoError(err)
+	}
+	assert.Equal("1) Continue? yes\n2) 0123456789abcdef\n3) 0123456789\n", buf.String())
+	assert.NoError(lp.Close())
+	assert.Equal("1) Continue? yes\n2) 0123456789abcdef\n3) 0123456789\n4) last\n", buf.String())
+	if _, err := lp.Write([]byte("after close\n")); err == nil {
+		t.Error("expected error writing after close")
+	}
+}
+
+func TestLinePrefixerFlushTimeout(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		buf bytes.Buffer
+	)
+	mux := shell.NewLineMux(writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return buf.Write(p)
+	}), shell.LineOptions{FlushTimeout: 10 * time.Millisecond})
+	lp := mux.Prefixer(func() string { return "> " })
+	_, err := lp.Write([]byte("prompt: "))
+	assertions{t: t}.NoError(err)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		got := buf.String()
+		mu.Unlock()
+		if got == "> prompt: \n" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("partial line wasn't flushed, got: %q", got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLineMuxConcurrentStreams(t *testing.T) {
+	var buf bytes.Buffer
+	mux := shell.NewLineMux(&buf, shell.LineOptions{})
+	const lines = 100
+	var wg sync.WaitGroup
+	for _, name := range []string{"out", "err", "foo"} {
+		lp := mux.Prefixer(func() string { return name + " " })
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				// split each line into many writes, to provoke interleaving
+				for _, part := range []string{name, "-", strconv.Itoa(i), "\n"} {
+					_, _ = lp.Write([]byte(part))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(got) != 3*lines {
+		t.Fatalf("want %d lines, got %d", 3*lines, len(got))
+	}
+	for _, line := range got {
+		name, rest, _ := strings.Cut(line, " ")
+		if !strings.HasPrefix(rest, name+"-") {
+			t.Errorf("interleaved line: %q", line)
+		}
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,46 @@
+//go:build !unix
+
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+const (
+	sigTerm = syscall.SIGTERM
+	sigKill = syscall.SIGKILL
+)
+
+// startProcessGroup is a no-op, as process groups aren't supported on this
+// platform.
+func startProcessGroup(*exec.Cmd) {}
+
+// signalProcessGroup kills only the process itself, as process groups aren't
+// supported on this platform.
+func signalProcessGroup(p *os.Process, _ syscall.Signal) error {
+	return p.Kill()
+}
+
+// exitSignal always returns nil, as the termination signal can't be detected
+// on this platform.
+func exitSignal(*os.ProcessState) os.Signal {
+	return nil
+}
@@ -17,11 +17,22 @@ limitations under the License.
 package shell
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"log"
+	"os"
+	"os/exec"
 	"path"
-	"regexp"
+	"path/filepath"
 	"runtime"
+	"strings"
+)
+
+const (
+	// RepoRootDirEnvVar is the name of the environment variable that points to
+	// the project root directory. It is also honoured by library.sh.
+	RepoRootDirEnvVar = "REPO_ROOT_DIR"
 )
 
 var (
@@ -32,17 +43,20 @@ var (
 	// outside of allowed places. This package is deprecated from start and was
 	// introduced to allow rewriting of shell code to Golang in small chunks.
 	ErrCallerNotAllowed = errors.New("don't try use knative.dev/hack/shell package outside of allowed places")
+
+	// ErrProjectRootNotFound is raised when the root directory of the project
+	// can't be found.
+	ErrProjectRootNotFound = errors.New("project root not found")
 )
 
 // NewProjectLocation creates a ProjectLocation that is used to calculate
 // relative paths within the project.
-func NewProjectLocation(pathToRoot string) (ProjectLocation, error) {
+func NewProjectLocation(pathToRoot string, opts ...LocationOption) (ProjectLocation, error) {
 	pc, filename, _, ok := runtime.Caller(1)
 	if !ok {
 		return nil, ErrCantGetCaller
 	}
-	funcName := runtime.FuncForPC(pc).Name()
-	err := isCallsiteAllowed(funcName)
+	err := checkCallsite(pc, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -65,17 +79,143 @@ type callerLocation struct {
 	pathToRoot string
 }
 
-func isCallsiteAllowed(funcName string) error {
-	validPaths := []string{
-		"knative.+/test/upgrade",
-		"knative(:?\\.dev/|-)hack/shell",
+// NewFixedProjectLocation creates a ProjectLocation that points to the given
+// absolute path of the project root directory.
+func NewFixedProjectLocation(rootPath string, opts ...LocationOption) (ProjectLocation, error) {
+	if err := checkCaller(opts); err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(rootPath) {
+		return nil, fmt.Errorf("%w: path isn't absolute: %s",
+			ErrProjectRootNotFound, rootPath)
+	}
+	return newFixedLocation(rootPath)
+}
+
+// NewGoModProjectLocation creates a ProjectLocation that points to the nearest
+// directory, starting from startDir and going up, that contains the go.mod
+// file. If modulePath isn't empty, the go.mod file needs to declare that
+// module. The empty startDir means the current working directory.
+func NewGoModProjectLocation(startDir, modulePath string, opts ...LocationOption) (ProjectLocation, error) {
+	if err := checkCaller(opts); err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(startDir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProjectRootNotFound, err)
+	}
+	for {
+		gomod := filepath.Join(dir, "go.mod")
+		if mod, err := goModulePath(gomod); err == nil {
+			if modulePath == "" || mod == modulePath {
+				return newFixedLocation(dir)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %w", ErrProjectRootNotFound, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if modulePath != "" {
+		return nil, fmt.Errorf("%w: no go.mod of %s module in %s or its parents",
+			ErrProjectRootNotFound, modulePath, startDir)
+	}
+	return nil, fmt.Errorf("%w: no go.mod in %s or its parents",
+		ErrProjectRootNotFound, startDir)
+}
+
+// NewGitProjectLocation creates a ProjectLocation that points to the top-level
+// directory of the Git repository containing the dir, as reported by
+// `git rev-parse --show-toplevel`. The empty dir means the current working
+// directory.
+func NewGitProjectLocation(dir string, opts ...LocationOption) (ProjectLocation, error) {
+	if err := checkCaller(opts); err != nil {
+		return nil, err
+	}
+	c := exec.Command("git", "rev-parse", "--show-toplevel")
+	c.Dir = dir
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: git rev-parse --show-toplevel: %w: %s",
+			ErrProjectRootNotFound, err, strings.TrimSpace(stderr.String()))
+	}
+	return newFixedLocation(strings.TrimSpace(string(out)))
+}
+
+// NewEnvProjectLocation creates a ProjectLocation that points to the directory
+// set in the REPO_ROOT_DIR environment variable.
+func NewEnvProjectLocation(opts ...LocationOption) (ProjectLocation, error) {
+	if err := checkCaller(opts); err != nil {
+		return nil, err
+	}
+	rootPath, ok := os.LookupEnv(RepoRootDirEnvVar)
+	if !ok || rootPath == "" {
+		return nil, fmt.Errorf("%w: %s environment variable isn't set",
+			ErrProjectRootNotFound, RepoRootDirEnvVar)
+	}
+	return newFixedLocation(rootPath)
+}
+
+// RootPath return a path to root of the project.
+func (f *fixedLocation) RootPath() string {
+	return f.rootPath
+}
+
+// fixedLocation holds an already resolved project root directory.
+type fixedLocation struct {
+	rootPath string
+}
+
+func newFixedLocation(rootPath string) (*fixedLocation, error) {
+	fi, err := os.Stat(rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProjectRootNotFound, err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%w: not a directory: %s",
+			ErrProjectRootNotFound, rootPath)
+	}
+	return &fixedLocation{rootPath: rootPath}, nil
+}
+
+// goModulePath returns the module path declared in the go.mod file.
+func goModulePath(gomod string) (string, error) {
+	bytes, err := os.ReadFile(gomod)
+	if err != nil {
+		return "", err
 	}
-	for _, validPath := range validPaths {
-		r := regexp.MustCompile(validPath)
-		if loc := r.FindStringIndex(funcName); loc != nil {
-			return nil
+	for _, line := range strings.Split(string(bytes), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`), nil
 		}
 	}
-	return fmt.Errorf("%w, tried using from: %s",
-		ErrCallerNotAllowed, funcName)
+	return "", fmt.Errorf("no module declared in %s", gomod)
+}
+
+// checkCaller verifies the caller of the public function calling it.
+func checkCaller(opts []LocationOption) error {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return ErrCantGetCaller
+	}
+	return checkCallsite(pc, opts)
+}
+
+func checkCallsite(pc uintptr, opts []LocationOption) error {
+	policy, err := callerPolicy(opts)
+	if err != nil {
+		return err
+	}
+	funcName := runtime.FuncForPC(pc).Name()
+	err = policy.Check(funcName)
+	if err != nil {
+		log.Printf("knative.dev/hack/shell: %v", err)
+	}
+	return err
 }
@@ -17,8 +17,10 @@ limitations under the License.
 package shell_test
 
 import (
+	"errors"
 	"os"
 	"path"
+	"path/filepath"
 	"testing"
 
 	"knative.dev/hack/shell"
@@ -33,3 +35,87 @@ func TestNewProjectLocation(t *testing.T) {
 	assert.NoError(err)
 	assert.Contains(string(bytes), "module knative.dev/hack")
 }
+
+func TestProjectLocationStrategies(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Dir(wd)
+	tests := []struct {
+		name    string
+		locate  func(t *testing.T) (shell.ProjectLocation, error)
+		want    string
+		wantErr error
+	}{{
+		name: "fixed",
+		locate: func(*testing.T) (shell.ProjectLocation, error) {
+			return shell.NewFixedProjectLocation(root)
+		},
+		want: root,
+	}, {
+		name: "fixed relative",
+		locate: func(*testing.T) (shell.ProjectLocation, error) {
+			return shell.NewFixedProjectLocation("..")
+		},
+		wantErr: shell.ErrProjectRootNotFound,
+	}, {
+		name: "go.mod",
+		locate: func(*testing.T) (shell.ProjectLocation, error) {
+			return shell.NewGoModProjectLocation("", "")
+		},
+		want: root,
+	}, {
+		name: "go.mod of module",
+		locate: func(*testing.T) (shell.ProjectLocation, error) {
+			return shell.NewGoModProjectLocation("../schema/registry", "knative.dev/hack/schema")
+		},
+		want: filepath.Join(root, "schema"),
+	}, {
+		name: "go.mod of unknown module",
+		locate: func(*testing.T) (shell.ProjectLocation, error) {
+			return shell.NewGoModProjectLocation("", "knative.dev/unknown")
+		},
+		wantErr: shell.ErrProjectRootNotFound,
+	}, {
+		name: "git",
+		locate: func(*testing.T) (shell.ProjectLocation, error) {
+			return shell.NewGitProjectLocation("")
+		},
+		want: root,
+	}, {
+		name: "git outside of repository",
+		locate: func(t *testing.T) (shell.ProjectLocation, error) {
+			t.Setenv("GIT_CEILING_DIRECTORIES", os.TempDir())
+			return shell.NewGitProjectLocation(t.TempDir())
+		},
+		wantErr: shell.ErrProjectRootNotFound,
+	}, {
+		name: "env",
+		locate: func(t *testing.T) (shell.ProjectLocation, error) {
+			t.Setenv(shell.RepoRootDirEnvVar, root)
+			return shell.NewEnvProjectLocation()
+		},
+		want: root,
+	}, {
+		name: "env unset",
+		locate: func(t *testing.T) (shell.ProjectLocation, error) {
+			t.Setenv(shell.RepoRootDirEnvVar, "")
+			return shell.NewEnvProjectLocation()
+		},
+		wantErr: shell.ErrProjectRootNotFound,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := tt.locate(t)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want error: %v\n got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			assert := assertions{t: t}
+			assert.Equal(tt.want, loc.RootPath())
+		})
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Interaction is a recorded call with its outcome.
+type Interaction struct {
+	Call
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	ExitCode int    `json:"exitCode"`
+	// Cause holds the message of the cause of a failed execution, that wasn't
+	// the exit code, like ErrTimedOut or ErrCanceled.
+	Cause string `json:"cause,omitempty"`
+	// Error holds the message of an error, that wasn't caused by the exit
+	// code.
+	Error string `json:"error,omitempty"`
+}
+
+// NewRecordingExecutor creates an Executor that delegates the calls to the
+// given executor, and records them with their outcome. The delegate should be
+// configured with the CaptureLimit, to record the output of the calls.
+func NewRecordingExecutor(delegate ContextExecutor) *RecordingExecutor {
+	return &RecordingExecutor{delegate: delegate}
+}
+
+// RecordingExecutor records the interactions with the delegated Executor, so
+// they could be replayed later with the FakeExecutor.
+type RecordingExecutor struct {
+	delegate     ContextExecutor
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// RunScript executes and records a shell script with args.
+func (r *RecordingExecutor) RunScript(script Script, args ...string) error {
+	_, err := r.RunScriptContext(context.Background(), script, args...)
+	return err
+}
+
+// RunFunction executes and records a shell function with args.
+func (r *RecordingExecutor) RunFunction(fn Function, args ...string) error {
+	_, err := r.RunFunctionContext(context.Background(), fn, args...)
+	return err
+}
+
+// RunScriptContext executes and records a shell script with args.
+func (r *RecordingExecutor) RunScriptContext(ctx context.Context, script Script, args ...string) (Result, error) {
+	res, err := r.delegate.RunScriptContext(ctx, script, args...)
+	r.record(ScriptCall(script, args...), res, err)
+	return res, err
+}
+
+// RunFunctionContext executes and records a shell function with args.
+func (r *RecordingExecutor) RunFunctionContext(ctx context.Context, fn Function, args ...string) (Result, error) {
+	res, err := r.delegate.RunFunctionContext(ctx, fn, args...)
+	r.record(FunctionCall(fn, args...), res, err)
+	return res, err
+}
+
+// Interactions returns the recorded interactions in order.
+func (r *RecordingExecutor) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.interactions)
+}
+
+// Save writes the recorded interactions to a JSON file.
+func (r *RecordingExecutor) Save(path string) error {
+	bytes, err := json.MarshalIndent(r.Interactions(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(bytes, '\n'), 0o600)
+}
+
+func (r *RecordingExecutor) record(c Call, res Result, err error) {
+	in := Interaction{
+		Call:     c,
+		Stdout:   string(res.Stdout),
+		Stderr:   string(res.Stderr),
+		ExitCode: res.ExitCode,
+	}
+	var exitErr *ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		if !errors.Is(exitErr.Cause, ErrNonZeroExit) {
+			in.Cause = exitErr.Cause.Error()
+		}
+	case err != nil:
+		in.Error = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, in)
+}
+
+// LoadInteractions reads the interactions saved by the RecordingExecutor.
+func LoadInteractions(path string) ([]Interaction, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var interactions []Interaction
+	if err = json.Unmarshal(bytes, &interactions); err != nil {
+		return nil, err
+	}
+	return interactions, nil
+}
+
+// ReplayResponses creates responses for the FakeExecutor, that replay the
+// interactions in order. Each response is used once, by a call of the same
+// script, function and args.
+func ReplayResponses(interactions []Interaction) []Response {
+	responses := make([]Response, len(interactions))
+	for i, in := range interactions {
+		args := in.Args
+		if args == nil {
+			args = []string{}
+		}
+		responses[i] = Response{
+			Label:        in.Label,
+			ScriptPath:   in.ScriptPath,
+			FunctionName: in.FunctionName,
+			Args:         args,
+			Times:        1,
+			Stdout:       in.Stdout,
+			Stderr:       in.Stderr,
+			ExitCode:     in.ExitCode,
+			Cause:        replayCause(in.Cause),
+		}
+		if in.Error != "" {
+			responses[i].Err = errors.New(in.Error)
+		}
+	}
+	return responses
+}
+
+// replayCause returns the known cause with the recorded message, so it could
+// be matched with errors.Is.
+func replayCause(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	for _, cause := range []error{ErrTimedOut, ErrCanceled, ErrNonZeroExit} {
+		if cause.Error() == msg {
+			return cause
+		}
+	}
+	return errors.New(msg)
+}
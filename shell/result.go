@@ -0,0 +1,94 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Result holds the outcome of a script or function execution.
+type Result struct {
+	// ExitCode is the exit code of the process, or -1 if it was killed by a
+	// signal.
+	ExitCode int
+	// StartTime is the time the process was started.
+	StartTime time.Time
+	// EndTime is the time the process has finished.
+	EndTime time.Time
+	// Signal is the signal that killed the process, if any.
+	Signal os.Signal
+	// Stdout holds the captured tail of the output stream, if capturing is
+	// enabled with ExecutorConfig.CaptureLimit.
+	Stdout []byte
+	// Stderr holds the captured tail of the error stream, if capturing is
+	// enabled with ExecutorConfig.CaptureLimit.
+	Stderr []byte
+}
+
+// Duration returns how long the execution took.
+func (r Result) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime)
+}
+
+// ExitError is returned if the executed script or function hasn't finished
+// successfully. The Cause is one of ErrNonZeroExit, ErrTimedOut or
+// ErrCanceled, and can be checked with errors.Is.
+type ExitError struct {
+	Label      string
+	ScriptPath string
+	Result
+	Cause error
+	Err   error
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s (%s): %v: %v", e.Label, e.ScriptPath, e.Cause, e.Err)
+}
+
+// Unwrap returns the cause and the underlying process error.
+func (e *ExitError) Unwrap() []error {
+	return []error{e.Cause, e.Err}
+}
+
+// newCapture creates a writer that keeps at most limit of the most recently
+// written bytes.
+func newCapture(limit int) *capture {
+	return &capture{limit: limit}
+}
+
+type capture struct {
+	limit int
+	buf   []byte
+}
+
+func (c *capture) Write(p []byte) (int, error) {
+	if c.limit <= 0 {
+		return len(p), nil
+	}
+	c.buf = append(c.buf, p...)
+	if over := len(c.buf) - c.limit; over > 0 {
+		c.buf = append(c.buf[:0], c.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// Bytes returns the captured bytes, or nil if nothing was captured.
+func (c *capture) Bytes() []byte {
+	return c.buf
+}
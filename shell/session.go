@@ -0,0 +1,494 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSessionCrashed is returned if the bash process of the session has
+	// exited unexpectedly. The session will be restarted on the next call.
+	ErrSessionCrashed = errors.New("session crashed")
+
+	// ErrNotSourced is returned if the function is defined in a different
+	// script than the one sourced by the session.
+	ErrNotSourced = errors.New("function's script isn't sourced by the session")
+)
+
+const markerLength = 16
+
+// NewSession creates a new session, that keeps a single bash process with the
+// given script sourced, and executes its functions one after another. The
+// bash process is started on the first call.
+func NewSession(config ExecutorConfig, script Script) *Session {
+	configureDefaultValues(&config)
+	return &Session{
+		ExecutorConfig: config,
+		script:         script,
+	}
+}
+
+// Session is a long-lived bash process, with a script sourced only once,
+// that executes many functions of that script. The session is safe for
+// concurrent use, but the functions are executed one at a time.
+type Session struct {
+	ExecutorConfig
+	script Script
+	mu     sync.Mutex
+	proc   *sessionProcess
+}
+
+// RunFunctionContext executes a shell function with args within the session.
+// The session is started, or restarted if it crashed before. The whole
+// session is terminated when the context is done before the function
+// finishes.
+func (s *Session) RunFunctionContext(ctx context.Context, fn Function, args ...string) (Result, error) {
+	if err := validate(s.ExecutorConfig); err != nil {
+		return Result{}, err
+	}
+	if fn.ScriptPath != s.script.ScriptPath {
+		return Result{}, fmt.Errorf("%w: %s, session sources: %s",
+			ErrNotSourced, fn.ScriptPath, s.script.ScriptPath)
+	}
+	argv := append([]string{fn.FunctionName}, args...)
+	if err := validateArgs(argv); err != nil {
+		return Result{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.proc != nil && !s.proc.running() {
+		_ = s.proc.close(s.GracePeriod)
+		s.proc = nil
+	}
+	if s.proc == nil {
+		p, err := startSession(ctx, s.ExecutorConfig, s.script)
+		if err != nil {
+			return Result{}, err
+		}
+		s.proc = p
+	}
+	res, err := s.proc.call(ctx, s.ExecutorConfig, fn.Script, argv)
+	if s.proc.broken {
+		_ = s.proc.close(s.GracePeriod)
+		s.proc = nil
+	}
+	return res, err
+}
+
+// Close stops the bash process of the session, if it's running.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.proc == nil {
+		return nil
+	}
+	err := s.proc.close(s.GracePeriod)
+	s.proc = nil
+	return err
+}
+
+// sessionProcess is a bash process that reads function calls from its
+// standard input. After each call, it writes a marker to both output streams,
+// and the exit code to the status stream (fd 3).
+type sessionProcess struct {
+	bin    string
+	cmd    *exec.Cmd
+	group  *processGroup
+	stdin  io.WriteCloser
+	out    *frameReader
+	err    *frameReader
+	status chan int
+	stop   chan struct{}
+	broken bool
+	// statusErr is set before the status channel is closed, if the status
+	// stream was malformed.
+	statusErr error
+}
+
+func startSession(ctx context.Context, cfg ExecutorConfig, script Script) (*sessionProcess, error) {
+	nonce := make([]byte, markerLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	marker := hex.EncodeToString(nonce)
+	bin, err := writeTempScript(script.sessionContent(cfg.ProjectLocation, marker))
+	if err != nil {
+		return nil, err
+	}
+	p := &sessionProcess{
+		bin:    bin,
+		cmd:    exec.Command(bin),
+		status: make(chan int),
+		stop:   make(chan struct{}),
+	}
+	if err = p.start(cfg, []byte("\x00"+marker+"\x00")); err != nil {
+		_ = os.Remove(bin)
+		return nil, err
+	}
+	// the sourcing of the script is reported as the first call
+	if _, err = p.call(ctx, cfg, script, nil); err != nil {
+		_ = p.close(cfg.GracePeriod)
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *sessionProcess) start(cfg ExecutorConfig, marker []byte) error {
+	var parentEnds, childEnds []*os.File
+	closeAll := func(files []*os.File) {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+	for i := 0; i < 3; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			closeAll(parentEnds)
+			closeAll(childEnds)
+			return err
+		}
+		parentEnds = append(parentEnds, r)
+		childEnds = append(childEnds, w)
+	}
+	stdin, err := p.cmd.StdinPipe()
+	if err != nil {
+		closeAll(parentEnds)
+		closeAll(childEnds)
+		return err
+	}
+	p.stdin = stdin
+	p.cmd.Env = cfg.Environ
+	p.cmd.Stdout = childEnds[0]
+	p.cmd.Stderr = childEnds[1]
+	p.cmd.ExtraFiles = childEnds[2:]
+	startProcessGroup(p.cmd)
+	err = p.cmd.Start()
+	// the child process has its own copies of the write ends
+	closeAll(childEnds)
+	if err != nil {
+		closeAll(parentEnds)
+		return err
+	}
+	p.out = newFrameReader(marker, p.stop)
+	p.err = newFrameReader(marker, p.stop)
+	go p.out.run(parentEnds[0])
+	go p.err.run(parentEnds[1])
+	go p.readStatus(parentEnds[2])
+	p.group = &processGroup{p: p.cmd.Process, exited: make(chan struct{})}
+	go func() {
+		_ = p.group.wait(p.cmd)
+	}()
+	return nil
+}
+
+func (p *sessionProcess) call(ctx context.Context, cfg ExecutorConfig, script Script, argv []string) (Result, error) {
+	outs := newOutputs(cfg, script.Label)
+	res := Result{StartTime: time.Now()}
+	outs.events.start(res.StartTime)
+	p.out.setWriter(outs.stdout)
+	p.err.setWriter(outs.stderr)
+	var err error
+	if argv != nil {
+		stdin := os.DevNull
+		if script.Stdin != "" {
+			if stdin, err = writeTempFile("shellout-stdin-*", script.Stdin, 0o600); err == nil {
+				defer func(name string) {
+					_ = os.Remove(name)
+				}(stdin)
+			}
+		}
+		if err == nil {
+			err = p.send(script, stdin, argv)
+		}
+	}
+	if err == nil {
+		res.ExitCode, err = p.wait(ctx, cfg.GracePeriod)
+	}
+	res.EndTime = time.Now()
+	// detach the writers, as the output might still be read after a failure
+	p.out.setWriter(nil)
+	p.err.setWriter(nil)
+	outs.finish(&res)
+	if err != nil {
+		p.broken = true
+		return res, &ExitError{
+			Label:      script.Label,
+			ScriptPath: script.ScriptPath,
+			Result:     res,
+			Cause:      failureCause(ctx, ErrSessionCrashed),
+			Err:        err,
+		}
+	}
+	if res.ExitCode != 0 {
+		return res, &ExitError{
+			Label:      script.Label,
+			ScriptPath: script.ScriptPath,
+			Result:     res,
+			Cause:      ErrNonZeroExit,
+			Err:        fmt.Errorf("exit status %d", res.ExitCode),
+		}
+	}
+	return res, nil
+}
+
+// send writes the function call as NUL separated fields: the working
+// directory, the file with the standard input, and lists of environment
+// variables to set and unset, and arguments, each preceded by its length.
+func (p *sessionProcess) send(script Script, stdin string, argv []string) error {
+	var buf bytes.Buffer
+	field := func(s string) {
+		buf.WriteString(s)
+		buf.WriteByte(0)
+	}
+	list := func(items []string) {
+		field(strconv.Itoa(len(items)))
+		for _, item := range items {
+			field(item)
+		}
+	}
+	dir := script.Dir
+	if dir == "" {
+		dir = "."
+	}
+	field(dir)
+	field(stdin)
+	list(script.Environ)
+	list(script.Unsetenv)
+	list(argv)
+	_, err := p.stdin.Write(buf.Bytes())
+	return err
+}
+
+func (p *sessionProcess) wait(ctx context.Context, gracePeriod time.Duration) (int, error) {
+	var code int
+	select {
+	case c, ok := <-p.status:
+		if !ok {
+			return -1, p.exitErr(ctx, gracePeriod)
+		}
+		code = c
+	case <-ctx.Done():
+		p.group.terminate(gracePeriod)
+		return -1, ctx.Err()
+	}
+	// markers are written before the status, so they are already in the pipes
+	for _, frames := range []<-chan struct{}{p.out.frames, p.err.frames} {
+		if _, ok := <-frames; !ok {
+			return -1, p.exitErr(ctx, gracePeriod)
+		}
+	}
+	return code, nil
+}
+
+// exitErr waits for the bash process to exit, unless the status stream was
+// malformed, and returns why the call didn't finish.
+func (p *sessionProcess) exitErr(ctx context.Context, gracePeriod time.Duration) error {
+	if p.statusErr != nil {
+		return p.statusErr
+	}
+	select {
+	case <-p.group.exited:
+	case <-ctx.Done():
+		p.group.terminate(gracePeriod)
+		return ctx.Err()
+	}
+	if p.cmd.ProcessState != nil {
+		return fmt.Errorf("bash %s", p.cmd.ProcessState)
+	}
+	return io.ErrUnexpectedEOF
+}
+
+func (p *sessionProcess) readStatus(r io.ReadCloser) {
+	defer close(p.status)
+	defer r.Close()
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		code, err := strconv.Atoi(sc.Text())
+		if err != nil {
+			p.statusErr = fmt.Errorf("malformed status frame %q: %w", sc.Text(), err)
+			return
+		}
+		select {
+		case p.status <- code:
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *sessionProcess) running() bool {
+	select {
+	case <-p.group.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *sessionProcess) close(gracePeriod time.Duration) error {
+	// bash will exit after reading EOF
+	_ = p.stdin.Close()
+	select {
+	case <-p.group.exited:
+	case <-time.After(gracePeriod):
+		p.group.terminate(gracePeriod)
+		<-p.group.exited
+	}
+	close(p.stop)
+	return os.Remove(p.bin)
+}
+
+func newFrameReader(marker []byte, stop <-chan struct{}) *frameReader {
+	return &frameReader{
+		marker: marker,
+		frames: make(chan struct{}),
+		stop:   stop,
+	}
+}
+
+// frameReader forwards the stream to the writer of the current call, and
+// signals each time the marker, which ends the call, is found in the stream.
+type frameReader struct {
+	marker []byte
+	frames chan struct{}
+	stop   <-chan struct{}
+	mu     sync.Mutex
+	w      io.Writer
+}
+
+func (f *frameReader) setWriter(w io.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.w = w
+}
+
+func (f *frameReader) write(p []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.w != nil && len(p) > 0 {
+		_, _ = f.w.Write(p)
+	}
+}
+
+func (f *frameReader) run(r io.ReadCloser) {
+	defer close(f.frames)
+	defer r.Close()
+	var buf []byte
+	chunk := make([]byte, 4096)
+	for {
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		for {
+			i := bytes.Index(buf, f.marker)
+			if i < 0 {
+				break
+			}
+			f.write(buf[:i])
+			buf = buf[i+len(f.marker):]
+			select {
+			case f.frames <- struct{}{}:
+			case <-f.stop:
+				return
+			}
+		}
+		// hold back only what could be the beginning of the next marker
+		keep := partialMarker(buf, f.marker)
+		f.write(buf[:len(buf)-keep])
+		buf = append([]byte(nil), buf[len(buf)-keep:]...)
+		if err != nil {
+			f.write(buf)
+			return
+		}
+	}
+}
+
+// partialMarker returns the length of the longest suffix of buf, which is
+// a beginning of the marker.
+func partialMarker(buf, marker []byte) int {
+	for k := min(len(buf), len(marker)-1); k > 0; k-- {
+		if bytes.HasPrefix(marker, buf[len(buf)-k:]) {
+			return k
+		}
+	}
+	return 0
+}
+
+func (sc *Script) sessionContent(location ProjectLocation, marker string) string {
+	return fmt.Sprintf(`#!/usr/bin/env bash
+
+set -Eeuo pipefail
+
+cd %s
+source %s
+
+set +Eeuo pipefail
+
+function __shell_session_frame() {
+  printf '\0%%s\0' '%s'
+  printf '\0%%s\0' '%s' >&2
+  echo "$1" >&3
+}
+
+# Reads a length of the list, and its items into __shell_session_items array.
+function __shell_session_read() {
+  local count item i
+  IFS= read -r -d '' count || return 1
+  __shell_session_items=()
+  for ((i = 0; i < count; i++)); do
+    IFS= read -r -d '' item || return 1
+    __shell_session_items+=("$item")
+  done
+}
+
+__shell_session_frame 0
+while IFS= read -r -d '' __shell_session_dir; do
+  IFS= read -r -d '' __shell_session_stdin || break
+  __shell_session_read || break
+  __shell_session_env=("${__shell_session_items[@]}")
+  __shell_session_read || break
+  __shell_session_unset=("${__shell_session_items[@]}")
+  __shell_session_read || break
+  __shell_session_argv=("${__shell_session_items[@]}")
+  (
+    cd "${__shell_session_dir}" || exit
+    for __shell_session_var in "${__shell_session_unset[@]}"; do
+      unset "${__shell_session_var}"
+    done
+    for __shell_session_var in "${__shell_session_env[@]}"; do
+      export "${__shell_session_var}"
+    done
+    set -Eeuo pipefail
+    "${__shell_session_argv[@]}"
+  ) < "${__shell_session_stdin}"
+  __shell_session_frame "$?"
+done
+`, shellQuote(location.RootPath()), shellQuote(sc.ScriptPath), marker, marker)
+}
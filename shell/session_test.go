@@ -0,0 +1,131 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"knative.dev/hack/shell"
+)
+
+func TestSession(t *testing.T) {
+	assert := assertions{t: t}
+	var outB, errB, events bytes.Buffer
+	cfg := config(t, func(cfg *shell.ExecutorConfig) {
+		cfg.Out = &outB
+		cfg.Err = &errB
+		cfg.Events = shell.NewJSONLinesSink(&events)
+		cfg.SkipDate = true
+		cfg.CaptureLimit = 1024
+		cfg.GracePeriod = 100 * time.Millisecond
+	})
+	script := shell.Script{
+		Label:      "session-example.sh",
+		ScriptPath: "shell/testdata/session-example.sh",
+	}
+	sess := shell.NewSession(cfg, script)
+	defer func() {
+		assert.NoError(sess.Close())
+	}()
+	ctx := context.Background()
+	call := func(name string, args ...string) (shell.Result, error) {
+		return sess.RunFunctionContext(ctx, shell.Function{
+			Script:       shell.Script{Label: name, ScriptPath: script.ScriptPath},
+			FunctionName: name,
+		}, args...)
+	}
+
+	res, err := call("greet", "World", "$HOME")
+	assert.NoError(err)
+	assert.Equal("Hello, World $HOME!\nno newline", string(res.Stdout))
+	assert.Equal("session-example.sh [OUT] sourced\n"+
+		"greet [OUT] Hello, World $HOME!\n"+
+		"greet [OUT] no newline", outB.String())
+
+	res, err = call("session_id")
+	assert.NoError(err)
+	id := string(res.Stdout)
+
+	res, err = call("fail", "expected err")
+	if !errors.Is(err, shell.ErrNonZeroExit) {
+		t.Errorf("want %v, got: %v", shell.ErrNonZeroExit, err)
+	}
+	if res.ExitCode != 3 {
+		t.Errorf("want exit code 3, got: %d", res.ExitCode)
+	}
+	assert.Equal("expected err\n", string(res.Stderr))
+	assert.Contains(events.String(),
+		`"type":"line","label":"fail","stream":"err","line":"expected err"}`)
+	assert.Contains(events.String(), `"type":"exit","label":"fail","exitCode":3}`)
+
+	res, err = call("session_id")
+	assert.NoError(err)
+	assert.Equal(id, string(res.Stdout))
+
+	_, err = call("crash")
+	if !errors.Is(err, shell.ErrSessionCrashed) {
+		t.Errorf("want %v, got: %v", shell.ErrSessionCrashed, err)
+	}
+
+	res, err = call("session_id")
+	assert.NoError(err)
+	if string(res.Stdout) == id {
+		t.Errorf("session wasn't restarted, id: %s", id)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = sess.RunFunctionContext(tctx, shell.Function{
+		Script:       shell.Script{Label: "sleep", ScriptPath: script.ScriptPath},
+		FunctionName: "sleep",
+	}, "10")
+	if !errors.Is(err, shell.ErrTimedOut) {
+		t.Errorf("want %v, got: %v", shell.ErrTimedOut, err)
+	}
+
+	res, err = sess.RunFunctionContext(ctx, shell.Function{
+		Script: shell.Script{
+			Label:      "overlay",
+			ScriptPath: script.ScriptPath,
+			Environ:    []string{"GREETING=Hi"},
+			Unsetenv:   []string{"HOME"},
+			Dir:        "pkg",
+			Stdin:      "from stdin",
+		},
+		FunctionName: "bash",
+	}, "-c", `echo "$(basename "$PWD") ${GREETING} ${HOME:-unset} $(cat)"`)
+	assert.NoError(err)
+	assert.Equal("pkg Hi unset from stdin\n", string(res.Stdout))
+
+	_, err = sess.RunFunctionContext(ctx, shell.Function{
+		Script:       shell.Script{Label: "status", ScriptPath: script.ScriptPath},
+		FunctionName: "bash",
+	}, "-c", "echo garbage >&3")
+	if !errors.Is(err, shell.ErrSessionCrashed) {
+		t.Errorf("want %v, got: %v", shell.ErrSessionCrashed, err)
+	}
+	assert.Contains(err.Error(), `malformed status frame "garbage"`)
+
+	_, err = sess.RunFunctionContext(ctx, fn("echo"))
+	if !errors.Is(err, shell.ErrNotSourced) {
+		t.Errorf("want %v, got: %v", shell.ErrNotSourced, err)
+	}
+}
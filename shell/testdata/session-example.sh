@@ -0,0 +1,39 @@
+#!/usr/bin/env bash
+
+# Copyright 2026 The Knative Authors
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     https://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Functions used to test the shell.Session.
+
+SESSION_ID="${RANDOM}-${RANDOM}"
+echo "sourced"
+
+function session_id() {
+  echo "${SESSION_ID}"
+}
+
+function greet() {
+  echo "Hello, $*!"
+  printf 'no newline'
+}
+
+function fail() {
+  echo "$*" >&2
+  return 3
+}
+
+function crash() {
+  # $$ is the PID of the session, not of the subshell
+  kill -KILL $$
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// tracePrelude directs the bash xtrace to the file descriptor 3, with each
+// line prefixed with the file, line and function of the traced command. The
+// variables are exported, so the scripts run by bash are traced the same way.
+const tracePrelude = `export BASH_XTRACEFD=3
+export PS4='+ ${BASH_SOURCE[0]:-}:${LINENO}:${FUNCNAME[0]:-main}: '
+`
+
+// traceDrainPeriod is how long the rest of the trace is read after the
+// process exits. The trace written before is already in the pipe, so it's
+// short, and doesn't wait for background processes holding the pipe open.
+const traceDrainPeriod = 100 * time.Millisecond
+
+// TraceSink receives the bash xtrace of the executed scripts and functions.
+// The error of storing the trace is returned by the execution, joined with
+// its own error.
+type TraceSink interface {
+	Trace(script Script, res Result, trace []byte) error
+}
+
+// Tracing holds the configuration of the bash xtrace capturing.
+type Tracing struct {
+	// TraceSink enables capturing of the bash xtrace, separately from the
+	// output and error streams, and receives the traces of failed executions.
+	TraceSink TraceSink
+	// TraceAlways passes the traces of successful executions to the TraceSink
+	// as well.
+	TraceAlways bool
+}
+
+// NewTraceDirSink creates a TraceSink that writes each trace to a new file
+// in the directory, named after the label of the execution.
+func NewTraceDirSink(dir string) TraceSink {
+	return traceDirSink{dir: dir}
+}
+
+// NewTraceWriterSink creates a TraceSink that writes the traces to the writer,
+// each preceded by a header line. The sink is safe for concurrent use.
+func NewTraceWriterSink(writer io.Writer) TraceSink {
+	return &traceWriterSink{writer: writer}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+type traceDirSink struct {
+	dir string
+}
+
+func (t traceDirSink) Trace(script Script, _ Result, trace []byte) error {
+	name := unsafeFileChars.ReplaceAllString(script.Label, "_")
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(t.dir, name+"-*.trace")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(trace); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type traceWriterSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func (t *traceWriterSink) Trace(script Script, res Result, trace []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := fmt.Fprintf(t.writer, "--- trace of %s (%s), exit code %d\n",
+		script.Label, path.Base(script.ScriptPath), res.ExitCode); err != nil {
+		return err
+	}
+	_, err := t.writer.Write(trace)
+	return err
+}
+
+// traceCapture reads the bash xtrace from a dedicated pipe.
+type traceCapture struct {
+	r    *os.File
+	w    *os.File
+	buf  bytes.Buffer
+	done chan struct{}
+}
+
+// startTraceCapture passes the write end of the trace pipe to the command as
+// the file descriptor 3.
+func startTraceCapture(c *exec.Cmd) (*traceCapture, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	c.ExtraFiles = []*os.File{w}
+	return &traceCapture{r: r, w: w, done: make(chan struct{})}, nil
+}
+
+// started closes the parent copy of the write end, and starts reading.
+func (t *traceCapture) started() {
+	_ = t.w.Close()
+	go func() {
+		defer close(t.done)
+		_, _ = io.Copy(&t.buf, t.r)
+	}()
+}
+
+// finish waits for the trace to be read, but no longer than the drain period,
+// as the pipe might be held open by a background process.
+func (t *traceCapture) finish() []byte {
+	select {
+	case <-t.done:
+	case <-time.After(traceDrainPeriod):
+		_ = t.r.Close()
+		<-t.done
+	}
+	_ = t.r.Close()
+	return t.buf.Bytes()
+}
+
+func (t *traceCapture) close() {
+	_ = t.w.Close()
+	_ = t.r.Close()
+}
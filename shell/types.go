@@ -16,7 +16,11 @@ limitations under the License.
 
 package shell
 
-import "io"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // ProjectLocation represents a project location on a file system.
 type ProjectLocation interface {
@@ -25,8 +29,20 @@ type ProjectLocation interface {
 
 // Script represents a script to be executed.
 type Script struct {
-	Label      string
-	ScriptPath string
+	Label      string `json:"label"`
+	ScriptPath string `json:"scriptPath"`
+	// Environ holds additional environment variables, in the KEY=value form,
+	// set over the ExecutorConfig.Environ for this script only.
+	Environ []string `json:"environ,omitempty"`
+	// Unsetenv holds names of environment variables removed from the
+	// ExecutorConfig.Environ for this script only. The Environ is applied
+	// after it.
+	Unsetenv []string `json:"unsetenv,omitempty"`
+	// Dir is the working directory, relative to the project root. The empty
+	// Dir means the project root.
+	Dir string `json:"dir,omitempty"`
+	// Stdin is the content of the standard input.
+	Stdin string `json:"stdin,omitempty"`
 }
 
 // Function represents a function, whom will be sourced from Script file,
@@ -40,8 +56,20 @@ type Function struct {
 type ExecutorConfig struct {
 	ProjectLocation
 	Streams
+	// Events receives machine-readable events of the executions, in addition
+	// to the prefixed text written to Streams. It must be safe for concurrent
+	// use.
+	Events EventSink
 	Labels
+	Tracing
 	Environ []string
+	// GracePeriod is the time to wait after sending SIGTERM to the process
+	// group of the cancelled script, before it will be killed with SIGKILL.
+	GracePeriod time.Duration
+	// CaptureLimit is the maximum number of bytes of each of the output and
+	// error streams to be kept in the Result. The most recent bytes are kept.
+	// Zero disables the capturing.
+	CaptureLimit int
 }
 
 // StreamType represets either output or error stream.
@@ -65,6 +93,9 @@ type Labels struct {
 	LabelErr   string
 	SkipDate   bool
 	DateFormat string
+	// Color controls the ANSI escape sequences in the prefixed output, see
+	// ColorMode.
+	Color ColorMode
 	PrefixFunc
 }
 
@@ -72,6 +103,11 @@ type Labels struct {
 type Streams struct {
 	Out io.Writer
 	Err io.Writer
+	// LineBuffered writes the output a whole prefixed line at a time, see
+	// NewLinePrefixer. If Out and Err are the same writer, or the same
+	// LineMux, the lines of both streams don't interleave.
+	LineBuffered bool
+	LineOptions
 }
 
 // Executor represents a executor that can execute shell scripts and call
@@ -80,3 +116,11 @@ type Executor interface {
 	RunScript(script Script, args ...string) error
 	RunFunction(fn Function, args ...string) error
 }
+
+// ContextExecutor is an Executor that can also be cancelled with a context,
+// and returns the Result of the calls.
+type ContextExecutor interface {
+	Executor
+	RunScriptContext(ctx context.Context, script Script, args ...string) (Result, error)
+	RunFunctionContext(ctx context.Context, fn Function, args ...string) (Result, error)
+}